@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"yegorov-boris/affise-test-task/pkg/client"
+)
+
+// listFlag collects the values of a repeated flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	return fs
+}
+
+// parseID parses the flags followed by the job ID, flags are also accepted after the ID.
+func parseID(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if fs.NArg() == 0 {
+		return "", &usageError{msg: "job ID is required"}
+	}
+
+	id := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+
+	if fs.NArg() != 0 {
+		return "", &usageError{msg: fmt.Sprintf("unexpected arguments %v", fs.Args())}
+	}
+
+	return id, nil
+}
+
+func (a *app) submit(ctx context.Context, args []string) (int, error) {
+	var (
+		opts    client.SubmitOptions
+		selects listFlag
+	)
+	fs := newFlagSet("submit")
+	file := fs.String("f", "", `file of links, one per line or a JSON array, "-" for stdin`)
+	wait := fs.Bool("wait", false, "wait for the job to finish and print its result")
+	format := fs.String("o", formatTable, "output format: table, json or ndjson")
+	interval := fs.Duration("interval", 500*time.Millisecond, "polling interval with -wait")
+	timeout := fs.Duration("timeout", 0, "maximum time to wait with -wait, 0 means no limit")
+	extract := fs.String("extract", "", "comma separated extractors: title, meta, links and text")
+	fs.Var(&selects, "select", "dotted path of a value selected from JSON bodies, repeatable")
+	fs.BoolVar(&opts.Normalize, "normalize", false, "normalize links before deduplicating them")
+	fs.BoolVar(&opts.SortQuery, "sort-query", false, "normalize links and sort their query parameters")
+	fs.BoolVar(&opts.DropBody, "drop-body", false, "store outputs without bodies")
+	fs.BoolVar(&opts.NoCache, "no-cache", false, "fetch the links instead of serving cached responses")
+	fs.StringVar(&opts.IdempotencyKey, "idempotency-key", "", "Idempotency-Key header")
+	fs.StringVar(&opts.CompareTo, "compare-to", "", "ID of a submitted job to detect changes against")
+	fs.BoolVar(&opts.Diff, "diff", false, "add unified diffs of the changed bodies")
+	if err := fs.Parse(args); err != nil {
+		return errorCode(err), err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return exitUsage, err
+	}
+
+	links, err := readLinks(fs.Args(), *file)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if len(*extract) != 0 {
+		opts.Extract = strings.Split(*extract, ",")
+	}
+	opts.Select = selects
+	if len(opts.CompareTo) != 0 {
+		if opts.CompareToken, err = a.jobs.token(a.addr, opts.CompareTo, ""); err != nil {
+			return errorCode(err), err
+		}
+	}
+
+	job, err := a.client.Submit(ctx, links, opts)
+	if err != nil {
+		return exitError, err
+	}
+
+	if err := a.jobs.add(jobRecord{Addr: a.addr, ID: job.ID, Token: job.Token, SubmittedAt: time.Now()}); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+	}
+
+	if !*wait {
+		return exitOK, printJob(os.Stdout, job, *format)
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	result, err := a.client.Wait(ctx, job.ID, job.Token, *interval)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "job %s is still in progress\n", job.ID)
+
+		return exitInProgress, printJob(os.Stdout, job, *format)
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	return resultCode(result), printResult(os.Stdout, result, *format)
+}
+
+func (a *app) get(ctx context.Context, args []string) (int, error) {
+	fs := newFlagSet("get")
+	token := fs.String("token", "", "access token of the job, taken from the jobs file by default")
+	format := fs.String("o", formatTable, "output format: table, json or ndjson")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return exitUsage, err
+	}
+
+	t, err := a.jobs.token(a.addr, id, *token)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	result, err := a.client.Get(ctx, id, t)
+	if errors.Is(err, client.ErrInProgress) {
+		fmt.Fprintf(os.Stderr, "job %s is in progress\n", id)
+
+		return exitInProgress, nil
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	return resultCode(result), printResult(os.Stdout, result, *format)
+}
+
+func (a *app) wait(ctx context.Context, args []string) (int, error) {
+	fs := newFlagSet("wait")
+	token := fs.String("token", "", "access token of the job, taken from the jobs file by default")
+	format := fs.String("o", formatTable, "output format: table, json or ndjson")
+	interval := fs.Duration("interval", 500*time.Millisecond, "polling interval")
+	timeout := fs.Duration("timeout", 0, "maximum time to wait, 0 means no limit")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return exitUsage, err
+	}
+
+	t, err := a.jobs.token(a.addr, id, *token)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	result, err := a.client.Wait(ctx, id, t, *interval)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "job %s is still in progress\n", id)
+
+		return exitInProgress, nil
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	return resultCode(result), printResult(os.Stdout, result, *format)
+}
+
+func (a *app) cancel(ctx context.Context, args []string) (int, error) {
+	fs := newFlagSet("cancel")
+	token := fs.String("token", "", "access token of the job, taken from the jobs file by default")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	t, err := a.jobs.token(a.addr, id, *token)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if err := a.client.Cancel(ctx, id, t); err != nil {
+		return exitError, err
+	}
+
+	return exitOK, nil
+}
+
+// list shows the states of the jobs submitted to the API from this machine.
+func (a *app) list(ctx context.Context, args []string) (int, error) {
+	fs := newFlagSet("list")
+	format := fs.String("o", formatTable, "output format: table, json or ndjson")
+	if err := fs.Parse(args); err != nil {
+		return errorCode(err), err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return exitUsage, err
+	}
+
+	records, err := a.jobs.load()
+	if err != nil {
+		return exitError, err
+	}
+
+	type jobState struct {
+		ID          string    `json:"id"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		State       string    `json:"state"`
+	}
+	var states []jobState
+	for _, r := range records {
+		if r.Addr != a.addr {
+			continue
+		}
+
+		state := "finished"
+		result, err := a.client.Get(ctx, r.ID, r.Token)
+		var apiErr *client.APIError
+		switch {
+		case errors.Is(err, client.ErrInProgress):
+			state = "in progress"
+		case errors.As(err, &apiErr):
+			state = fmt.Sprintf("unavailable (%d)", apiErr.StatusCode)
+		case err != nil:
+			return exitError, err
+		case len(result.Error) != 0:
+			state = "failed"
+		case result.Failed():
+			state = "assertions failed"
+		}
+		states = append(states, jobState{ID: r.ID, SubmittedAt: r.SubmittedAt, State: state})
+	}
+
+	switch *format {
+	case formatJSON:
+		return exitOK, writeJSON(os.Stdout, states, true)
+	case formatNDJSON:
+		for _, s := range states {
+			if err := writeJSON(os.Stdout, s, false); err != nil {
+				return exitError, err
+			}
+		}
+
+		return exitOK, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSUBMITTED\tSTATE")
+	for _, s := range states {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.ID, s.SubmittedAt.Format(time.DateTime), s.State)
+	}
+
+	return exitOK, w.Flush()
+}
+
+func (a *app) tail(ctx context.Context, args []string) (int, error) {
+	fs := newFlagSet("tail")
+	token := fs.String("token", "", "access token of the job, taken from the jobs file by default")
+	format := fs.String("o", formatTable, "output format: table for text lines, json or ndjson")
+	interval := fs.Duration("interval", 500*time.Millisecond, "polling interval")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return exitUsage, err
+	}
+
+	t, err := a.jobs.token(a.addr, id, *token)
+	if err != nil {
+		return errorCode(err), err
+	}
+
+	var errPrint error
+	result, err := a.client.Watch(ctx, id, t, *interval, func(e client.Event) {
+		if errPrint == nil {
+			errPrint = printEvent(os.Stdout, e, *format)
+		}
+	})
+	if err != nil {
+		return exitError, err
+	}
+
+	return resultCode(result), errPrint
+}
+
+// readLinks takes the links from args, the file or stdin when it is piped.
+// Files have a link per line, blank lines and lines starting with # are skipped, or a JSON array of links.
+func readLinks(args []string, file string) ([]string, error) {
+	links := append([]string(nil), args...)
+
+	var r io.Reader
+	switch {
+	case file == "-":
+		r = os.Stdin
+	case len(file) != 0:
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %q: %w", file, err)
+		}
+		defer f.Close()
+		r = f
+	case len(args) == 0:
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+			r = os.Stdin
+		}
+	}
+
+	if r != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read links: %w", err)
+		}
+
+		if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+			var fromJSON []string
+			if err := json.Unmarshal(trimmed, &fromJSON); err != nil {
+				return nil, fmt.Errorf("failed to decode links from JSON: %w", err)
+			}
+			links = append(links, fromJSON...)
+		} else {
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if len(line) != 0 && !strings.HasPrefix(line, "#") {
+					links = append(links, line)
+				}
+			}
+		}
+	}
+
+	if len(links) == 0 {
+		return nil, &usageError{msg: "no links to submit, pass them as arguments, with -f or on stdin"}
+	}
+
+	return links, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// jobsFile keeps the access tokens of the submitted jobs, as the API has no way to list jobs
+// and every job requires its token.
+type jobsFile struct {
+	path string
+}
+
+type jobRecord struct {
+	Addr        string    `json:"addr"`
+	ID          string    `json:"id"`
+	Token       string    `json:"token"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+func (f *jobsFile) load() ([]jobRecord, error) {
+	var records []jobRecord
+
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read jobs file %q: %w", f.path, err)
+	}
+
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs file %q from JSON: %w", f.path, err)
+	}
+
+	return records, nil
+}
+
+func (f *jobsFile) add(record jobRecord) error {
+	records, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, r := range records {
+		if r.Addr == record.Addr && r.ID == record.ID {
+			records[i], replaced = record, true
+		}
+	}
+	if !replaced {
+		records = append(records, record)
+	}
+
+	b, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON encode jobs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create jobs file directory: %w", err)
+	}
+
+	// the file keeps access tokens
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("failed to write jobs file %q: %w", tmp, err)
+	}
+
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to rename %q to %q: %w", tmp, f.path, err)
+	}
+
+	return nil
+}
+
+// token returns the token passed in flags or the one saved on submit.
+func (f *jobsFile) token(addr, id, token string) (string, error) {
+	if len(token) != 0 {
+		return token, nil
+	}
+
+	records, err := f.load()
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range records {
+		if r.Addr == addr && r.ID == id {
+			return r.Token, nil
+		}
+	}
+
+	return "", &usageError{msg: fmt.Sprintf("access token of job %s is unknown, pass it with -token", id)}
+}
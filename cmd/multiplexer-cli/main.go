@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"yegorov-boris/affise-test-task/pkg/client"
+)
+
+// Exit codes reflect the job outcome
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitJobFailed
+	exitAssertionsFailed
+	exitInProgress
+)
+
+const usage = `Usage: multiplexer-cli [global flags] <command> [flags] [args]
+
+Commands:
+  submit [links...]  submit links from args, a file (-f) or stdin
+  get <id>           print the result of a job
+  wait <id>          wait for a job to finish and print its result
+  cancel <id>        cancel a job in progress or remove its result
+  list               list the jobs submitted from this machine with their states
+  tail <id>          print the events of a job until it finishes
+
+Exit codes: 0 success, 1 error, 2 usage, 3 job failed, 4 assertions failed, 5 job in progress.
+
+Global flags:
+`
+
+type app struct {
+	addr   string
+	client *client.Client
+	jobs   *jobsFile
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	global := flag.NewFlagSet("multiplexer-cli", flag.ContinueOnError)
+	addr := global.String("addr", envOr("MULTIPLEXER_ADDR", "http://127.0.0.1:8080/api/v1/links"), "base URL of the API, env MULTIPLEXER_ADDR")
+	apiKey := global.String("api-key", os.Getenv("MULTIPLEXER_API_KEY"), "API key, env MULTIPLEXER_API_KEY")
+	jobsPath := global.String("jobs-file", defaultJobsPath(), "file keeping the IDs and the access tokens of submitted jobs")
+	global.Usage = func() {
+		fmt.Fprint(global.Output(), usage)
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+		return exitUsage
+	}
+
+	a := &app{
+		addr:   *addr,
+		client: client.New(*addr, client.WithAPIKey(*apiKey)),
+		jobs:   &jobsFile{path: *jobsPath},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	commands := map[string]func(context.Context, []string) (int, error){
+		"submit": a.submit,
+		"get":    a.get,
+		"wait":   a.wait,
+		"cancel": a.cancel,
+		"list":   a.list,
+		"tail":   a.tail,
+	}
+	command, ok := commands[global.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", global.Arg(0))
+		global.Usage()
+		return exitUsage
+	}
+
+	code, err := command(ctx, global.Args()[1:])
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+	}
+
+	return code
+}
+
+// resultCode maps the job outcome to the exit code.
+func resultCode(result *client.Result) int {
+	switch {
+	case len(result.Error) != 0:
+		return exitJobFailed
+	case result.Failed():
+		return exitAssertionsFailed
+	default:
+		return exitOK
+	}
+}
+
+// errorCode maps the errors of flags parsing to the usage exit code.
+func errorCode(err error) int {
+	var usageErr *usageError
+	if errors.Is(err, flag.ErrHelp) || errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	return exitError
+}
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func envOr(name, value string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+
+	return value
+}
+
+func defaultJobsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "multiplexer-cli-jobs.json"
+	}
+
+	return filepath.Join(dir, "multiplexer-cli", "jobs.json")
+}
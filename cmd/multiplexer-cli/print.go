@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+	"yegorov-boris/affise-test-task/pkg/client"
+)
+
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+func validateFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatNDJSON:
+		return nil
+	default:
+		return &usageError{msg: fmt.Sprintf("unknown output format %q, should be table, json or ndjson", format)}
+	}
+}
+
+func printJob(w io.Writer, job client.Job, format string) error {
+	if format != formatTable {
+		return writeJSON(w, job, format == formatJSON)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTOKEN")
+	fmt.Fprintf(tw, "%s\t%s\n", job.ID, job.Token)
+
+	return tw.Flush()
+}
+
+// printResult writes the outputs of the job, the error of a failed job goes to stderr in the table format.
+func printResult(w io.Writer, result *client.Result, format string) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, result, true)
+	case formatNDJSON:
+		if len(result.Error) != 0 {
+			return writeJSON(w, map[string]string{"error": result.Error}, false)
+		}
+
+		for _, output := range result.Outputs {
+			if err := writeJSON(w, output, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(result.Error) != 0 {
+		fmt.Fprintf(os.Stderr, "job failed: %s\n", result.Error)
+
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tSTATUS\tBYTES\tCACHED\tVERDICT\tCHANGE")
+	for _, output := range result.Outputs {
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%d\t%t\t%s\t%s\n",
+			output.URL,
+			output.StatusCode,
+			len(output.Body),
+			output.Cached,
+			verdict(output.Verdict),
+			change(output.Change),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func printEvent(w io.Writer, e client.Event, format string) error {
+	if format != formatTable {
+		return writeJSON(w, e, format == formatJSON)
+	}
+
+	line := fmt.Sprintf("%s %s job %s", e.Time.Format(time.TimeOnly), e.Type, e.JobID)
+	if e.Output != nil {
+		line += fmt.Sprintf(" %s %d %s", e.Output.URL, e.Output.StatusCode, verdict(e.Output.Verdict))
+	}
+	if len(e.Error) != 0 {
+		line += ": " + e.Error
+	}
+
+	_, err := fmt.Fprintln(w, line)
+
+	return err
+}
+
+func verdict(v *client.Verdict) string {
+	if v == nil {
+		return "-"
+	}
+
+	if v.Passed {
+		return "passed"
+	}
+
+	return "failed"
+}
+
+func change(c *client.Change) string {
+	switch {
+	case c == nil:
+		return "-"
+	case c.New:
+		return "new"
+	case c.Changed:
+		return "changed"
+	default:
+		return "unchanged"
+	}
+}
+
+func writeJSON(w io.Writer, v any, indent bool) error {
+	encoder := json.NewEncoder(w)
+	if indent {
+		encoder.SetIndent("", "  ")
+	}
+
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
@@ -1,34 +1,71 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 	"yegorov-boris/affise-test-task/configs"
 	"yegorov-boris/affise-test-task/internal/multiplexer"
 	"yegorov-boris/affise-test-task/pkg/dotenv"
 )
 
 func main() {
-	if err := dotenv.Load(".env"); err != nil {
-		log.Fatalf("failed to parse .env file: %s", err)
+	loader := configs.NewLoader(flag.CommandLine)
+	printConfig := flag.Bool("print-config", false, "print the effective config with the source of each value and exit")
+	flag.Parse()
+
+	load := func() (*configs.Config, error) {
+		if err := loadDotenv(); err != nil {
+			return nil, err
+		}
+
+		return loader.Load()
+	}
+
+	if *printConfig {
+		if err := loadDotenv(); err != nil {
+			log.Fatal(err)
+		}
+		if err := loader.Print(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	cfg, err := configs.New()
+	cfg, err := load()
 	if err != nil {
 		log.Fatalf("failed to create config: %s", err)
 	}
 
-	shutdown, err := multiplexer.Run(cfg)
+	reload, shutdown, err := multiplexer.Run(cfg, load)
 	if err != nil {
 		log.Fatalf("failed to start multiplexer: %s", err)
 	}
 
-	// Graceful shutdown
+	// Config reload on SIGHUP, graceful shutdown on SIGINT and SIGTERM
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := <-c; sig == syscall.SIGHUP; sig = <-c {
+		if _, err := reload(); err != nil {
+			log.Printf("failed to reload config: %s", err)
+		}
+	}
 	if err := shutdown(); err != nil {
 		log.Fatalf("graceful shutdown failed: %s", err)
 	}
 }
+
+// loadDotenv sets env vars from the optional .env file.
+func loadDotenv() error {
+	if err := dotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to parse .env file: %w", err)
+	}
+
+	return nil
+}
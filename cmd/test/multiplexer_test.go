@@ -101,11 +101,13 @@ func Test_Multiplexer(t *testing.T) {
 							URL:        testLink("1"),
 							StatusCode: http.StatusOK,
 							Body:       string(bodies["1"]),
+							BodyHash:   models.ContentHash(string(bodies["1"])),
 						},
 						{
 							URL:        testLink("2"),
 							StatusCode: http.StatusOK,
 							Body:       string(bodies["2"]),
+							BodyHash:   models.ContentHash(string(bodies["2"])),
 						},
 					},
 				},
@@ -132,6 +134,7 @@ func Test_Multiplexer(t *testing.T) {
 							URL:        testLink("3"),
 							StatusCode: http.StatusOK,
 							Body:       string(bodies["3"]),
+							BodyHash:   models.ContentHash(string(bodies["3"])),
 						},
 					},
 				},
@@ -208,7 +211,7 @@ func Test_Multiplexer(t *testing.T) {
 			mainConfig.MaxParallelIn = tt.rateLimit
 			mainConfig.HTTPClientTimeout = tt.httpClientTimeout
 			mainConfig.StorePath = "../../store"
-			shutdown, err := multiplexer.Run(mainConfig)
+			_, shutdown, err := multiplexer.Run(mainConfig, configs.New)
 			if err != nil {
 				t.Error(err)
 				return
@@ -273,6 +276,11 @@ func Test_Multiplexer(t *testing.T) {
 						errs[i] = fmt.Errorf("Invalid response body: %s", err)
 						return
 					}
+					accessToken := res.Header.Get("X-Access-Token")
+					if accessToken == "" {
+						errs[i] = fmt.Errorf("Access token not found in response headers")
+						return
+					}
 
 					if tt.shutdown {
 						shutdownOnce()
@@ -293,6 +301,7 @@ func Test_Multiplexer(t *testing.T) {
 							errs[i] = err
 							return
 						}
+						req.Header.Set("X-Access-Token", accessToken)
 						res, err := http.DefaultClient.Do(req)
 						if err != nil {
 							errs[i] = fmt.Errorf("Failed to cancel: %s", err)
@@ -305,7 +314,13 @@ func Test_Multiplexer(t *testing.T) {
 					}
 
 					time.Sleep(2 * tt.httpClientTimeout)
-					res, err = http.Get(fmt.Sprintf("%s/%d", multiplexerAPI, id))
+					req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d", multiplexerAPI, id), nil)
+					if err != nil {
+						errs[i] = err
+						return
+					}
+					req.Header.Set("X-Access-Token", accessToken)
+					res, err = http.DefaultClient.Do(req)
 					if err != nil {
 						errs[i] = err
 						return
@@ -2,109 +2,179 @@ package configs
 
 import (
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
+	"reflect"
 	"strconv"
 	"time"
+	"yegorov-boris/affise-test-task/internal/models"
 )
 
+const (
+	IDFormatSequential = "sequential"
+	IDFormatRandom     = "random"
+
+	RateLimitByIP     = "ip"
+	RateLimitByAPIKey = "key"
+
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+
+	TracingExporterNone = "none"
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
+// Config fields are loaded by Loader. The env tag names the env var, the flag and the config file key
+// are derived from it: HTTP_PORT is set by -http-port flag and "http_port" key.
 type Config struct {
-	StorePath            string
-	StoreTimeout         time.Duration
-	HTTPPort             uint32
-	HTTPBasePath         string
-	HTTPClientTimeout    time.Duration
-	GracefulShutdownStep time.Duration
-	MaxLinksPerIn        uint32
-	MaxParallelIn        uint32
-	MaxParallelOutPerIn  uint32
+	StorePath              string        `env:"STORE_PATH" default:"./store" desc:"directory of stored outputs"`
+	StoreTimeout           time.Duration `env:"STORE_TIMEOUT" default:"5m" desc:"time to keep stored outputs"`
+	IdempotencyWindow      time.Duration `env:"IDEMPOTENCY_WINDOW" default:"5m" desc:"time to return the original job for a POST repeated with the same Idempotency-Key, 0 disables keys"`
+	HTTPPort               uint32        `env:"HTTP_PORT" default:"8080" desc:"HTTP server port"`
+	HTTPBasePath           string        `env:"HTTP_BASE_PATH" default:"/api/v1/links" desc:"base path of the API, without trailing slash"`
+	HTTPReadHeaderTimeout  time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" desc:"time to read request headers"`
+	HTTPReadTimeout        time.Duration `env:"HTTP_READ_TIMEOUT" default:"30s" desc:"time to read a request, 0 means no timeout"`
+	HTTPWriteTimeout       time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"60s" desc:"time to write a response, 0 means no timeout"`
+	HTTPIdleTimeout        time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s" desc:"time to keep an idle keep-alive connection"`
+	HTTPMaxHeaderBytes     uint32        `env:"HTTP_MAX_HEADER_BYTES" default:"1048576" desc:"maximum size of request headers"`
+	HTTP2                  bool          `env:"HTTP2" default:"true" desc:"serve HTTP/2 over TLS"`
+	TLSCertFile            string        `env:"TLS_CERT_FILE" desc:"TLS certificate file, plain HTTP when empty"`
+	TLSKeyFile             string        `env:"TLS_KEY_FILE" desc:"TLS private key file" secret:"true"`
+	TLSReloadInterval      time.Duration `env:"TLS_RELOAD_INTERVAL" default:"10s" desc:"interval of checking TLS files for changes"`
+	UnixSocket             string        `env:"UNIX_SOCKET" desc:"path of an additional plain HTTP Unix socket listener"`
+	CoalesceRequests       bool          `env:"COALESCE_REQUESTS" default:"true" desc:"share one outgoing request between concurrent requests of the same link"`
+	CacheMaxBytes          uint32        `env:"CACHE_MAX_BYTES" default:"0" desc:"maximum size of cached responses, no cache when 0"`
+	HTTPClientTimeout      time.Duration `env:"HTTP_CLIENT_TIMEOUT" default:"1s" desc:"timeout of an outgoing request" reload:"true"`
+	MaxLinksPerIn          uint32        `env:"MAX_LINKS_PER_IN" default:"20" desc:"maximum links per incoming request" reload:"true"`
+	MaxParallelIn          uint32        `env:"MAX_PARALLEL_IN" default:"100" desc:"maximum concurrently processed incoming requests" reload:"true"`
+	MaxParallelOutPerIn    uint32        `env:"MAX_PARALLEL_OUT_PER_IN" default:"4" desc:"maximum concurrent outgoing requests per incoming one" reload:"true"`
+	IDFormat               string        `env:"ID_FORMAT" default:"sequential" desc:"IDs format: sequential or random"`
+	APIKeysFile            string        `env:"API_KEYS_FILE" desc:"JSON file of API keys, no authentication when empty" secret:"true"`
+	RateLimitRPS           float64       `env:"RATE_LIMIT_RPS" default:"0" desc:"requests per second per client, no limit when 0"`
+	RateLimitBurst         uint32        `env:"RATE_LIMIT_BURST" default:"10" desc:"requests burst per client"`
+	RateLimitBy            string        `env:"RATE_LIMIT_BY" default:"ip" desc:"rate limit clients by ip or key"`
+	RateLimitMaxClients    uint32        `env:"RATE_LIMIT_MAX_CLIENTS" default:"10000" desc:"maximum rate limited clients kept in memory"`
+	TrustedProxies         []string      `env:"TRUSTED_PROXIES" desc:"comma separated IPs or CIDRs of proxies trusted to set X-Forwarded-For"`
+	ReadinessMaxSaturation float64       `env:"READINESS_MAX_SATURATION" default:"0.9" desc:"share of MAX_PARALLEL_IN in use making the service not ready"`
+	ShutdownDrainDelay     time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"0s" desc:"time between failing readiness and stopping the HTTP server on shutdown"`
+	ShutdownTimeout        time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s" desc:"time to wait for jobs on shutdown before interrupting them"`
+	ForwardRequestID       bool          `env:"FORWARD_REQUEST_ID" default:"false" desc:"pass X-Request-ID on outgoing requests"`
+	LogFormat              string        `env:"LOG_FORMAT" default:"text" desc:"logs format: text or json"`
+	LogLevel               slog.Level    `env:"LOG_LEVEL" default:"info" desc:"logs level: debug, info, warn or error"`
+	LogOutput              string        `env:"LOG_OUTPUT" default:"stdout" desc:"logs output: stdout, stderr or a file path"`
+	TracingExporter        string        `env:"TRACING_EXPORTER" default:"none" desc:"spans exporter: none, otlp or file"`
+	TracingEndpoint        string        `env:"TRACING_ENDPOINT" desc:"OTLP/HTTP traces URL of the collector" secret:"true"`
+	TracingFile            string        `env:"TRACING_FILE" desc:"file of exported spans"`
+	TracingServiceName     string        `env:"TRACING_SERVICE_NAME" default:"multiplexer" desc:"service name reported in spans"`
 }
 
+// New loads the config from defaults, the config file and env vars.
 func New() (*Config, error) {
-	cfg := new(Config)
+	return NewLoader(nil).Load()
+}
 
-	if err := cfg.parse(); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %s", err)
+func (c *Config) validate() error {
+	if len(c.HTTPBasePath) != 0 && c.HTTPBasePath[len(c.HTTPBasePath)-1] == byte('/') {
+		return fmt.Errorf("%q parameter must not end with \"/\"", "HTTPBasePath")
 	}
 
-	if err := cfg.validate(); err != nil {
-		return nil, fmt.Errorf("failed to validate config: %s", err)
+	if len(c.StorePath) == 0 {
+		return fmt.Errorf("%q parameter must not be empty", "StorePath")
 	}
 
-	return cfg, nil
-}
+	if c.IdempotencyWindow < 0 {
+		return fmt.Errorf("%q parameter must not be negative", "IdempotencyWindow")
+	}
 
-func (c *Config) parse() error {
-	var err error
+	if c.MaxLinksPerIn < 1 {
+		return fmt.Errorf("%q parameter must be at least 1", "MaxLinksPerIn")
+	}
 
-	c.StorePath = os.Getenv("STORE_PATH")
+	if c.MaxParallelIn < 1 {
+		return fmt.Errorf("%q parameter must be at least 1", "MaxParallelIn")
+	}
 
-	c.StoreTimeout, err = time.ParseDuration(os.Getenv("STORE_TIMEOUT"))
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "STORE_TIMEOUT", err)
+	if c.MaxParallelOutPerIn < 1 {
+		return fmt.Errorf("%q parameter must be at least 1", "MaxParallelOutPerIn")
 	}
 
-	c.HTTPPort, err = parseUint32("HTTP_PORT")
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "HTTP_PORT", err)
+	if c.MaxParallelOutPerIn > c.MaxLinksPerIn {
+		return fmt.Errorf("%q parameter must not be greater than %q parameter", "MaxParallelOutPerIn", "MaxLinksPerIn")
 	}
 
-	c.HTTPBasePath, err = url.JoinPath(os.Getenv("HTTP_BASE_PATH"), "")
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "HTTP_BASE_PATH", err)
+	if c.IDFormat != IDFormatSequential && c.IDFormat != IDFormatRandom {
+		return fmt.Errorf("%q parameter must be either %q or %q", "IDFormat", IDFormatSequential, IDFormatRandom)
 	}
 
-	c.HTTPClientTimeout, err = time.ParseDuration(os.Getenv("HTTP_CLIENT_TIMEOUT"))
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "HTTP_CLIENT_TIMEOUT", err)
+	if c.RateLimitRPS < 0 {
+		return fmt.Errorf("%q parameter must not be negative", "RateLimitRPS")
 	}
 
-	c.GracefulShutdownStep, err = time.ParseDuration(os.Getenv("GRACEFUL_SHUTDOWN_STEP"))
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "GRACEFUL_SHUTDOWN_STEP", err)
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		return fmt.Errorf("%q parameter must be at least 1 when %q parameter is set", "RateLimitBurst", "RateLimitRPS")
 	}
 
-	c.MaxLinksPerIn, err = parseUint32("MAX_LINKS_PER_IN")
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "MAX_LINKS_PER_IN", err)
+	if c.RateLimitBy != RateLimitByIP && c.RateLimitBy != RateLimitByAPIKey {
+		return fmt.Errorf("%q parameter must be either %q or %q", "RateLimitBy", RateLimitByIP, RateLimitByAPIKey)
 	}
 
-	c.MaxParallelIn, err = parseUint32("MAX_PARALLEL_IN")
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "MAX_PARALLEL_IN", err)
+	if c.RateLimitMaxClients < 1 {
+		return fmt.Errorf("%q parameter must be at least 1", "RateLimitMaxClients")
 	}
 
-	c.MaxParallelOutPerIn, err = parseUint32("MAX_PARALLEL_OUT_PER_IN")
-	if err != nil {
-		return fmt.Errorf("failed to parse %q env var: %w", "MAX_PARALLEL_OUT_PER_IN", err)
+	if c.ReadinessMaxSaturation <= 0 || c.ReadinessMaxSaturation > 1 {
+		return fmt.Errorf("%q parameter must be greater than 0 and not greater than 1", "ReadinessMaxSaturation")
 	}
 
-	return nil
-}
+	if c.ShutdownDrainDelay < 0 {
+		return fmt.Errorf("%q parameter must not be negative", "ShutdownDrainDelay")
+	}
 
-func (c *Config) validate() error {
-	if len(c.HTTPBasePath) != 0 && c.HTTPBasePath[len(c.HTTPBasePath)-1] == byte('/') {
-		return fmt.Errorf("%q parameter must not end with \"/\"", "HTTPBasePath")
+	if c.ShutdownTimeout <= c.ShutdownDrainDelay {
+		return fmt.Errorf("%q parameter must be greater than %q parameter", "ShutdownTimeout", "ShutdownDrainDelay")
 	}
 
-	if len(c.StorePath) == 0 {
-		return fmt.Errorf("%q parameter must not be empty", "StorePath")
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		return fmt.Errorf("%q parameter must be either %q or %q", "LogFormat", LogFormatText, LogFormatJSON)
 	}
 
-	if c.MaxLinksPerIn < 1 {
-		return fmt.Errorf("%q parameter must be at least 1", "MaxLinksPerIn")
+	switch c.TracingExporter {
+	case TracingExporterNone:
+	case TracingExporterOTLP:
+		if u, err := url.Parse(c.TracingEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("%q parameter must be a valid HTTP URL when %q parameter is %q", "TracingEndpoint", "TracingExporter", TracingExporterOTLP)
+		}
+	case TracingExporterFile:
+		if len(c.TracingFile) == 0 {
+			return fmt.Errorf("%q parameter must not be empty when %q parameter is %q", "TracingFile", "TracingExporter", TracingExporterFile)
+		}
+	default:
+		return fmt.Errorf("%q parameter must be one of %q, %q or %q", "TracingExporter", TracingExporterNone, TracingExporterOTLP, TracingExporterFile)
 	}
 
-	if c.MaxParallelIn < 1 {
-		return fmt.Errorf("%q parameter must be at least 1", "MaxParallelIn")
+	if c.HTTPReadHeaderTimeout <= 0 {
+		return fmt.Errorf("%q parameter must be positive", "HTTPReadHeaderTimeout")
 	}
 
-	if c.MaxParallelOutPerIn < 1 {
-		return fmt.Errorf("%q parameter must be at least 1", "MaxParallelOutPerIn")
+	if c.HTTPReadTimeout < 0 || c.HTTPWriteTimeout < 0 || c.HTTPIdleTimeout < 0 {
+		return fmt.Errorf("%q, %q and %q parameters must not be negative", "HTTPReadTimeout", "HTTPWriteTimeout", "HTTPIdleTimeout")
 	}
 
-	if c.MaxParallelOutPerIn > c.MaxLinksPerIn {
-		return fmt.Errorf("%q parameter must not be greater than %q parameter", "MaxParallelOutPerIn", "MaxLinksPerIn")
+	if c.HTTPMaxHeaderBytes < 1 {
+		return fmt.Errorf("%q parameter must be at least 1", "HTTPMaxHeaderBytes")
+	}
+
+	if (len(c.TLSCertFile) == 0) != (len(c.TLSKeyFile) == 0) {
+		return fmt.Errorf("%q and %q parameters must be either both set or both empty", "TLSCertFile", "TLSKeyFile")
+	}
+
+	if len(c.TLSCertFile) != 0 && c.TLSReloadInterval <= 0 {
+		return fmt.Errorf("%q parameter must be positive", "TLSReloadInterval")
 	}
 
 	if c.HTTPPort < 1 || c.HTTPPort >= (1<<16) {
@@ -114,6 +184,57 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// LogValue hides the values of the fields tagged with `secret:"true"`.
+func (c *Config) LogValue() slog.Value {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	attrs := make([]slog.Attr, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		attrs = append(attrs, slog.Any(t.Field(i).Name, displayValue(t.Field(i), v.Field(i))))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Diff lists the fields changed in other.
+// It fails when a field not tagged with `reload:"true"` is changed, as it can't be applied without restart.
+func (c *Config) Diff(other *Config) ([]models.ConfigChange, error) {
+	v := reflect.ValueOf(c).Elem()
+	otherV := reflect.ValueOf(other).Elem()
+	t := v.Type()
+	var changes []models.ConfigChange
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(v.Field(i).Interface(), otherV.Field(i).Interface()) {
+			continue
+		}
+
+		if t.Field(i).Tag.Get("reload") != "true" {
+			return nil, fmt.Errorf("%q parameter can not be changed without restart", t.Field(i).Name)
+		}
+
+		changes = append(changes, models.ConfigChange{
+			Field: t.Field(i).Name,
+			Old:   displayValue(t.Field(i), v.Field(i)),
+			New:   displayValue(t.Field(i), otherV.Field(i)),
+		})
+	}
+
+	return changes, nil
+}
+
+func displayValue(field reflect.StructField, v reflect.Value) any {
+	if field.Tag.Get("secret") == "true" && !v.IsZero() {
+		return "[REDACTED]"
+	}
+
+	value := v.Interface()
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+
+	return value
+}
+
 func parseUint32(name string) (uint32, error) {
 	u, err := strconv.ParseUint(os.Getenv(name), 10, 32)
 
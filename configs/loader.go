@@ -0,0 +1,268 @@
+package configs
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+
+	configFileEnv  = "CONFIG_FILE"
+	configFileFlag = "config"
+)
+
+// Loader builds the config from layers, each one overriding the previous:
+// defaults, the optional JSON config file, env vars and command line flags.
+type Loader struct {
+	flags      *flag.FlagSet
+	configFile *string
+}
+
+// NewLoader defines a flag per config field and -config flag in flags, which must be parsed before loading.
+// Flags are not used when flags is nil.
+func NewLoader(flags *flag.FlagSet) *Loader {
+	l := &Loader{
+		flags:      flags,
+		configFile: new(string),
+	}
+	if flags == nil {
+		return l
+	}
+
+	l.configFile = flags.String(configFileFlag, "", fmt.Sprintf("JSON config file, overrides %s env var", configFileEnv))
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		usage := field.Tag.Get("desc")
+		if d, ok := field.Tag.Lookup("default"); ok {
+			usage = fmt.Sprintf("%s (default %q)", usage, d)
+		}
+		flags.String(flagName(field), "", fmt.Sprintf("%s, overrides %s env var", usage, field.Tag.Get("env")))
+	}
+
+	return l
+}
+
+// Load returns a validated config.
+func (l *Loader) Load() (*Config, error) {
+	cfg, _, err := l.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Print writes the effective config with the source of each value, secrets are redacted.
+// It returns the validation error, if any, after printing it.
+func (l *Loader) Print(w io.Writer) error {
+	cfg, sources, err := l.load()
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "failed to parse config: %s\n", err)
+
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE")
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		_, _ = fmt.Fprintf(tw, "%s\t%v\t%s\n", t.Field(i).Tag.Get("env"), displayValue(t.Field(i), v.Field(i)), sources[t.Field(i).Name])
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		_, _ = fmt.Fprintf(w, "\ninvalid config: %s\n", err)
+
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	return nil
+}
+
+// load returns the config and the source of each field by field name.
+func (l *Loader) load() (*Config, map[string]string, error) {
+	cfg := new(Config)
+	sources := make(map[string]string)
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if d, ok := t.Field(i).Tag.Lookup("default"); ok {
+			if err := setField(v.Field(i), d); err != nil {
+				return nil, nil, fmt.Errorf("invalid default of %q parameter: %w", t.Field(i).Name, err)
+			}
+		}
+		sources[t.Field(i).Name] = SourceDefault
+	}
+
+	fileValues, err := l.readConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flagValues := make(map[string]string)
+	if l.flags != nil {
+		l.flags.Visit(func(f *flag.Flag) {
+			flagValues[f.Name] = f.Value.String()
+		})
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fileValue, inFile := fileValues[fileKey(field)]
+		delete(fileValues, fileKey(field))
+		envValue := os.Getenv(field.Tag.Get("env"))
+		flagValue, inFlags := flagValues[flagName(field)]
+
+		for _, layer := range []struct {
+			source string
+			name   string
+			value  string
+			ok     bool
+		}{
+			{SourceFile, fmt.Sprintf("%q key of config file", fileKey(field)), fileValue, inFile},
+			{SourceEnv, fmt.Sprintf("%q env var", field.Tag.Get("env")), envValue, len(envValue) != 0},
+			{SourceFlag, fmt.Sprintf("-%s flag", flagName(field)), flagValue, inFlags},
+		} {
+			if !layer.ok {
+				continue
+			}
+
+			if err := setField(v.Field(i), layer.value); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse %s: %w", layer.name, err)
+			}
+			sources[field.Name] = layer.source
+		}
+	}
+
+	for key := range fileValues {
+		return nil, nil, fmt.Errorf("unknown %q key in config file", key)
+	}
+
+	cfg.HTTPBasePath, err = url.JoinPath(cfg.HTTPBasePath, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %q parameter: %w", "HTTPBasePath", err)
+	}
+
+	return cfg, sources, nil
+}
+
+// readConfigFile returns the values of the config file as strings parsed the same way as env vars.
+func (l *Loader) readConfigFile() (map[string]string, error) {
+	name := *l.configFile
+	if len(name) == 0 {
+		name = os.Getenv(configFileEnv)
+	}
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode config file from JSON: %w", err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var (
+			s    string
+			list []string
+		)
+		switch {
+		case json.Unmarshal(value, &s) == nil:
+			values[key] = s
+		case json.Unmarshal(value, &list) == nil:
+			values[key] = strings.Join(list, ",")
+		default:
+			values[key] = string(value)
+		}
+	}
+
+	return values, nil
+}
+
+func setField(v reflect.Value, s string) error {
+	switch target := v.Addr().Interface().(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*target = d
+	case encoding.TextUnmarshaler:
+		return target.UnmarshalText([]byte(s))
+	case *string:
+		*target = s
+	case *uint32:
+		u, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return err
+		}
+		*target = uint32(u)
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*target = f
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*target = b
+	case *[]string:
+		*target = splitList(s)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// splitList parses a comma separated list skipping empty items.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) != 0 {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+func flagName(field reflect.StructField) string {
+	return strings.ReplaceAll(strings.ToLower(field.Tag.Get("env")), "_", "-")
+}
+
+func fileKey(field reflect.StructField) string {
+	return strings.ToLower(field.Tag.Get("env"))
+}
@@ -0,0 +1,109 @@
+package configs
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLoader_Load(t *testing.T) {
+	name := "./config.json"
+	tests := []struct {
+		name        string
+		file        string
+		env         map[string]string
+		args        []string
+		wantPort    uint32
+		wantSources map[string]string
+		wantErr     bool
+	}{
+		{
+			name:     "should use defaults",
+			wantPort: 8080,
+			wantSources: map[string]string{
+				"HTTPPort":  SourceDefault,
+				"StorePath": SourceDefault,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "should override defaults by the config file",
+			file:     `{"http_port": 9000, "store_path": "./data"}`,
+			wantPort: 9000,
+			wantSources: map[string]string{
+				"HTTPPort":  SourceFile,
+				"StorePath": SourceFile,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "should override the config file by env vars and env vars by flags",
+			file:     `{"http_port": 9000, "store_path": "./data"}`,
+			env:      map[string]string{"HTTP_PORT": "9001", "STORE_PATH": "./env"},
+			args:     []string{"-http-port", "9002"},
+			wantPort: 9002,
+			wantSources: map[string]string{
+				"HTTPPort":  SourceFlag,
+				"StorePath": SourceEnv,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "should fail for an unknown key in the config file",
+			file:    `{"http_prot": 9000}`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an invalid flag value",
+			args:    []string{"-http-port", "port"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//set up
+			t.Setenv(configFileEnv, "")
+			fields := reflect.TypeOf(Config{})
+			for i := 0; i < fields.NumField(); i++ {
+				t.Setenv(fields.Field(i).Tag.Get("env"), "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			loader := NewLoader(flags)
+			args := tt.args
+			if len(tt.file) != 0 {
+				if err := os.WriteFile(name, []byte(tt.file), 0644); err != nil {
+					t.Errorf("failed to write %q: %s", name, err)
+				}
+				args = append([]string{"-config", name}, args...)
+			}
+			if err := flags.Parse(args); err != nil {
+				t.Errorf("Parse() error = %v", err)
+				return
+			}
+
+			cfg, sources, err := loader.load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && cfg.HTTPPort != tt.wantPort {
+				t.Errorf("load() HTTPPort = %d, want %d", cfg.HTTPPort, tt.wantPort)
+			}
+			for field, source := range tt.wantSources {
+				if sources[field] != source {
+					t.Errorf("load() source of %s = %q, want %q", field, sources[field], source)
+				}
+			}
+
+			if len(tt.file) != 0 {
+				if err := os.Remove(name); err != nil {
+					t.Errorf("rm %q failed: %s", name, err)
+				}
+			}
+		})
+	}
+}
@@ -2,28 +2,91 @@ package contracts
 
 import (
 	"context"
+	"io"
+	"io/fs"
 	"net/http"
+	"time"
 	"yegorov-boris/affise-test-task/internal/models"
 )
 
 type (
 	State interface {
-		Start() (uint64, context.Context)
-		Finish(uint64)
-		Check(uint64) bool
-		Cancel(uint64) bool
+		Start(context.Context) (string, context.Context, error)
+		Finish(string)
+		Check(string) bool
+		Cancel(string) bool
 	}
 
 	Scraper interface {
-		Scrap(context.Context, models.Input) ([]models.Output, string)
+		Scrap(context.Context, models.Input, models.Check) ([]models.Output, string)
+	}
+
+	Extractor interface {
+		Extract(models.Output, models.Extraction) models.Output
 	}
 
 	Store interface {
-		Save(uint64, []models.Output, string)
+		Save(context.Context, string, []models.Output, string)
+		Open(string) (File, error)
+		Remove(string) (bool, error)
+		Purge() (int, error)
+		List() ([]models.StoreEntry, error)
+		SaveJob(models.Job) error
+		LoadJob(string) (models.Job, error)
+		UpdateJob(string, func(*models.Job)) error
+		SaveIdempotencyKey(models.IdempotencyKey, time.Time) error
+		LoadIdempotencyKey(string) (models.IdempotencyKey, error)
+		SaveSchedule(models.Schedule) error
+		ListSchedules() ([]models.Schedule, error)
+		RemoveSchedule(string) (bool, error)
+	}
+
+	Scheduler interface {
+		Create(models.Schedule) (models.Schedule, error)
+		List() []models.Schedule
+		Get(string) (models.Schedule, bool)
+		Delete(string) (bool, error)
+	}
+
+	// File is a stored result
+	File interface {
+		io.ReadSeekCloser
+		Stat() (fs.FileInfo, error)
 	}
 
 	HTTPClient interface {
-		Get(context.Context, string) (models.Output, error)
+		Get(context.Context, string, http.Header) (models.Output, error)
+	}
+
+	APIKeys interface {
+		Lookup(string) (APIKey, bool)
+		Named(string) (APIKey, bool)
+	}
+
+	APIKey interface {
+		KeyName() string
+		Allows(endpoint string) bool
+		LinksPerIn(defaultMax uint32) uint32
+		StartJob() bool
+		FinishJob()
+		ReserveLinks(uint32) bool
+		ReleaseLinks(uint32)
+	}
+
+	TokenBucket interface {
+		Allow(key string) models.RateLimit
+	}
+
+	Semaphore interface {
+		TryAcquire() bool
+		Release()
+	}
+
+	// ClientKeyFunc identifies the client sending a request
+	ClientKeyFunc = func(r *http.Request) string
+
+	HealthChecker interface {
+		Check() models.Health
 	}
 
 	Handler = func(w http.ResponseWriter, r *http.Request)
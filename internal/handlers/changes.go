@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+const (
+	compareToParam    = "compare_to"
+	compareTokenParam = "compare_token"
+)
+
+// compareTo returns the ID of the job to compare the outputs with, if any.
+// Jobs of schedules compare with the previous job of the schedule, clients pass
+// ?compare_to=<id>&compare_token=<access token of that job>.
+// It writes an error response and returns false with an error when the job can not be compared with,
+// so the callback of the request is called.
+func compareTo(w http.ResponseWriter, r *http.Request, state contracts.State, store contracts.Store) (string, bool, error) {
+	if id, ok := r.Context().Value(contracts.ContextKey("compareTo")).(string); ok {
+		return id, true, nil
+	}
+
+	query := r.URL.Query()
+	if len(query.Get(compareToParam)) == 0 {
+		return "", true, nil
+	}
+
+	id, err := models.ParseID(query.Get(compareToParam))
+	if err != nil {
+		http.Error(w, "compare_to parameter should be a job ID.", http.StatusBadRequest)
+
+		return "", false, fmt.Errorf("invalid %s parameter: %w", compareToParam, err)
+	}
+
+	t := query.Get(compareTokenParam)
+	if len(t) == 0 {
+		http.Error(w, "Access token of the job to compare with is required.", http.StatusUnauthorized)
+
+		return "", false, fmt.Errorf("%s parameter is required with %s", compareTokenParam, compareToParam)
+	}
+
+	job, err := store.LoadJob(id)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "Job to compare with is not found.", http.StatusBadRequest)
+
+			return "", false, fmt.Errorf("job %s to compare with is not found: %w", id, err)
+		}
+
+		http.Error(w, "Failed to read the job to compare with.", http.StatusInternalServerError)
+
+		return "", false, fmt.Errorf("failed to load job %s: %w", id, err)
+	}
+
+	if !verify(t, job) {
+		http.Error(w, "Invalid access token of the job to compare with.", http.StatusForbidden)
+
+		return "", false, fmt.Errorf("invalid access token of job %s to compare with", id)
+	}
+
+	if state.Check(id) {
+		http.Error(w, "Job to compare with is in progress.", http.StatusConflict)
+
+		return "", false, fmt.Errorf("job %s to compare with is in progress", id)
+	}
+
+	return id, true, nil
+}
+
+// loadOutputs reads the stored outputs of the job, failed jobs have no outputs.
+func loadOutputs(store contracts.Store, id string) ([]models.Output, error) {
+	f, err := store.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var outputs []models.Output
+	if err := json.NewDecoder(f).Decode(&outputs); err != nil {
+		return nil, fmt.Errorf("failed to decode outputs of job %s from JSON: %w", id, err)
+	}
+
+	return outputs, nil
+}
+
+// detectChanges compares the outputs of the links with the previous outputs of the same links.
+func detectChanges(links models.Input, outputs []models.Output, index []int, previous []models.Output, withDiff bool) []*models.Change {
+	byURL := make(map[string]*models.Output, len(previous))
+	for i := range previous {
+		if _, ok := byURL[previous[i].URL]; !ok {
+			byURL[previous[i].URL] = &previous[i]
+		}
+	}
+
+	changes := make([]*models.Change, len(links))
+	for i, link := range links {
+		change := models.NewChange(outputs[index[i]], byURL[link], withDiff)
+		changes[i] = &change
+	}
+
+	return changes
+}
+
+// changedParam parses ?changed=only of GET.
+func changedParam(r *http.Request) (bool, error) {
+	switch value := r.URL.Query().Get("changed"); value {
+	case "":
+		return false, nil
+	case "only":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid changed parameter %q", value)
+	}
+}
+
+// filterChanged keeps only the changed outputs. Outputs are filtered as raw JSON,
+// so the rest of their fields are kept as they are stored.
+func filterChanged(data []byte) ([]byte, error) {
+	var outputs []json.RawMessage
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		// failed jobs store the error message instead of outputs
+		return data, nil
+	}
+
+	changed := make([]json.RawMessage, 0, len(outputs))
+	for _, raw := range outputs {
+		var output struct {
+			Change *models.Change `json:"change"`
+		}
+		if err := json.Unmarshal(raw, &output); err != nil {
+			return nil, fmt.Errorf("failed to decode output from JSON: %w", err)
+		}
+
+		if output.Change != nil && output.Change.Changed {
+			changed = append(changed, raw)
+		}
+	}
+
+	return json.Marshal(changed)
+}
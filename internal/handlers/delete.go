@@ -6,7 +6,7 @@ import (
 	"yegorov-boris/affise-test-task/internal/contracts"
 )
 
-func NewDelete(basePath string, state contracts.State) contracts.HandlerWithErr {
+func NewDelete(basePath string, state contracts.State, store contracts.Store) contracts.HandlerWithErr {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		id, err := parseID(basePath, r.URL.Path)
 		if err != nil {
@@ -14,6 +14,11 @@ func NewDelete(basePath string, state contracts.State) contracts.HandlerWithErr
 
 			return fmt.Errorf("invalid ID: %w", err)
 		}
+		setJobID(r, id)
+
+		if ok, err := authorize(w, r, store, id); !ok {
+			return err
+		}
 
 		if state.Cancel(id) {
 			w.WriteHeader(http.StatusNoContent)
@@ -21,7 +26,20 @@ func NewDelete(basePath string, state contracts.State) contracts.HandlerWithErr
 			return nil
 		}
 
-		http.Error(w, "Request in progress not found by ID", http.StatusNotFound)
+		removed, err := store.Remove(id)
+		if err != nil {
+			http.Error(w, "Failed to remove output.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to remove output %s: %w", id, err)
+		}
+
+		if removed {
+			w.WriteHeader(http.StatusNoContent)
+
+			return nil
+		}
+
+		http.Error(w, "Request not found by ID", http.StatusNotFound)
 
 		return nil
 	}
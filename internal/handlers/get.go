@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"yegorov-boris/affise-test-task/internal/contracts"
 )
 
-func NewGet(basePath, storePath string, state contracts.State) contracts.HandlerWithErr {
+// resultCacheControl lets clients cache results, which are only accessible with their tokens.
+const resultCacheControl = "private, max-age=31536000, immutable"
+
+func NewGet(basePath string, state contracts.State, store contracts.Store) contracts.HandlerWithErr {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		id, err := parseID(basePath, r.URL.Path)
 		if err != nil {
@@ -17,8 +24,21 @@ func NewGet(basePath, storePath string, state contracts.State) contracts.Handler
 
 			return fmt.Errorf("invalid ID: %w", err)
 		}
+		setJobID(r, id)
+
+		if ok, err := authorize(w, r, store, id); !ok {
+			return err
+		}
+
+		changedOnly, err := changedParam(r)
+		if err != nil {
+			http.Error(w, `changed parameter should be "only".`, http.StatusBadRequest)
+
+			return err
+		}
 
 		if state.Check(id) {
+			w.Header().Set("Cache-Control", "no-store")
 			if _, err := fmt.Fprintf(w, "Your request is in progress. Please, try a bit later."); err != nil {
 				return fmt.Errorf("failed to write response body: %w", err)
 			}
@@ -26,23 +46,76 @@ func NewGet(basePath, storePath string, state contracts.State) contracts.Handler
 			return nil
 		}
 
-		path := filepath.Join(storePath, fmt.Sprintf("%d.json", id))
-		f, err := os.Open(path)
+		job, err := store.LoadJob(id)
+		if err == nil && job.AssertionsPassed != nil {
+			w.Header().Set(assertionsPassedHeader, strconv.FormatBool(*job.AssertionsPassed))
+		}
+
+		f, err := store.Open(id)
 		if err != nil {
-			http.Error(w, "Output not found by ID", http.StatusNotFound)
+			if errors.Is(err, fs.ErrNotExist) {
+				http.Error(w, "Output not found by ID", http.StatusNotFound)
+
+				return nil
+			}
 
-			return fmt.Errorf("failed to open file %q: %w", path, err)
+			http.Error(w, "Failed to read output.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to open output %s: %w", id, err)
 		}
 
-		if _, err := io.Copy(w, f); err != nil {
+		if err := serveResult(w, r, f, job.ResultHash, changedOnly); err != nil {
 			_ = f.Close()
-			return fmt.Errorf("failed to read file %q: %w", path, err)
+			http.Error(w, "Failed to read output.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to read output %s: %w", id, err)
 		}
 
 		if err := f.Close(); err != nil {
-			return fmt.Errorf("failed to close file %q: %w", path, err)
+			return fmt.Errorf("failed to close output %s: %w", id, err)
 		}
 
 		return nil
 	}
 }
+
+// serveResult writes the stored result with the caching headers, answering conditional and range requests.
+// Results never change once written, so the hash of the result is its ETag.
+func serveResult(w http.ResponseWriter, r *http.Request, f contracts.File, hash string, changedOnly bool) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat output: %w", err)
+	}
+
+	// results stored before hashing are hashed on the fly
+	if len(hash) == 0 {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		hash = hex.EncodeToString(h.Sum(nil))
+	}
+
+	var content io.ReadSeeker = f
+	if changedOnly {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+
+		if data, err = filterChanged(data); err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+		hash += "-changed"
+	}
+
+	w.Header().Set("ETag", strconv.Quote(hash))
+	w.Header().Set("Cache-Control", resultCacheControl)
+	http.ServeContent(w, r, "", info.ModTime(), content)
+
+	return nil
+}
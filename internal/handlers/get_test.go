@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/internal/services/progress"
+	"yegorov-boris/affise-test-task/internal/services/store"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+func TestNewGet(t *testing.T) {
+	storePath := "./store/"
+	basePath := "/api/v1/links"
+	finishedID := "100"
+	outputs := []models.Output{
+		{URL: "https://example.com/1", StatusCode: 200, Body: "same", Change: &models.Change{}},
+		{URL: "https://example.com/2", StatusCode: 200, Body: "new", Change: &models.Change{Changed: true, New: true}},
+	}
+	stored, _ := json.Marshal(outputs)
+	changed, _ := json.Marshal([]models.Output{outputs[1]})
+	etag := strconv.Quote(models.ContentHash(string(stored)))
+
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	s := store.New(slog.Default(), storePath)
+	accessToken, tokenHash, err := token.New()
+	if err != nil {
+		t.Errorf("token.New() error = %v", err)
+		return
+	}
+	inProgressID, _, err := state.Start(context.Background())
+	if err != nil {
+		t.Errorf("Start() error = %v", err)
+		return
+	}
+	for _, id := range []string{inProgressID, finishedID} {
+		if err := s.SaveJob(models.Job{ID: id, TokenHash: tokenHash, CreatedAt: time.Now()}); err != nil {
+			t.Errorf("SaveJob() error = %v", err)
+			return
+		}
+	}
+	s.Save(context.Background(), finishedID, outputs, "")
+	handler := NewGet(basePath, state, s)
+
+	tests := []struct {
+		name             string
+		id               string
+		query            string
+		header           http.Header
+		wantStatus       int
+		wantETag         string
+		wantCacheControl string
+		wantBody         string
+	}{
+		{
+			name:             "should serve the result with its ETag",
+			id:               finishedID,
+			wantStatus:       http.StatusOK,
+			wantETag:         etag,
+			wantCacheControl: resultCacheControl,
+			wantBody:         string(stored),
+		},
+		{
+			name:             "should answer a matching If-None-Match with 304",
+			id:               finishedID,
+			header:           http.Header{"If-None-Match": {etag}},
+			wantStatus:       http.StatusNotModified,
+			wantETag:         etag,
+			wantCacheControl: resultCacheControl,
+		},
+		{
+			name:             "should serve the result for another If-None-Match",
+			id:               finishedID,
+			header:           http.Header{"If-None-Match": {`"outdated"`}},
+			wantStatus:       http.StatusOK,
+			wantETag:         etag,
+			wantCacheControl: resultCacheControl,
+			wantBody:         string(stored),
+		},
+		{
+			name:             "should serve a range of the result",
+			id:               finishedID,
+			header:           http.Header{"Range": {"bytes=0-9"}},
+			wantStatus:       http.StatusPartialContent,
+			wantETag:         etag,
+			wantCacheControl: resultCacheControl,
+			wantBody:         string(stored[:10]),
+		},
+		{
+			name:             "should serve the changed outputs with their own ETag",
+			id:               finishedID,
+			query:            "changed=only",
+			wantStatus:       http.StatusOK,
+			wantETag:         strconv.Quote(models.ContentHash(string(stored)) + "-changed"),
+			wantCacheControl: resultCacheControl,
+			wantBody:         string(changed),
+		},
+		{
+			name:             "should not let clients cache a job in progress",
+			id:               inProgressID,
+			wantStatus:       http.StatusOK,
+			wantCacheControl: "no-store",
+			wantBody:         "Your request is in progress. Please, try a bit later.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, basePath+"/"+tt.id+"?"+tt.query, nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			r.Header.Set(accessTokenHeader, accessToken)
+			w := httptest.NewRecorder()
+
+			if err := handler(w, r); err != nil {
+				t.Errorf("NewGet() error = %v", err)
+				return
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("NewGet() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("NewGet() ETag = %s, want %s", got, tt.wantETag)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("NewGet() Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("NewGet() body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+
+	state.Finish(inProgressID)
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
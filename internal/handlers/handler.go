@@ -1,9 +1,23 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+const (
+	accessTokenHeader = "X-Access-Token"
+	accessTokenParam  = "token"
+
+	assertionsPassedHeader = "X-Assertions-Passed"
 )
 
 func lastPathPart(basePath, path string) string {
@@ -12,6 +26,90 @@ func lastPathPart(basePath, path string) string {
 	return strings.TrimPrefix(path, prefix)
 }
 
-func parseID(basePath, path string) (uint64, error) {
-	return strconv.ParseUint(lastPathPart(basePath, path), 10, 64)
+func parseID(basePath, path string) (string, error) {
+	return models.ParseID(lastPathPart(basePath, path))
+}
+
+// setJobID reports the ID of the job the request relates to, so it gets to the access log.
+func setJobID(r *http.Request, id string) {
+	if jobID, ok := r.Context().Value(contracts.ContextKey("jobID")).(*string); ok {
+		*jobID = id
+	}
+}
+
+// authorize checks the job access token passed either in the header or in the query.
+// It writes an error response and returns false when access is denied.
+func authorize(w http.ResponseWriter, r *http.Request, store contracts.Store, id string) (bool, error) {
+	t := r.Header.Get(accessTokenHeader)
+	if len(t) == 0 {
+		t = r.URL.Query().Get(accessTokenParam)
+	}
+
+	if len(t) == 0 {
+		http.Error(w, "Access token is required.", http.StatusUnauthorized)
+
+		return false, nil
+	}
+
+	job, err := store.LoadJob(id)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "Request not found by ID", http.StatusNotFound)
+
+			return false, nil
+		}
+
+		http.Error(w, "Failed to read request.", http.StatusInternalServerError)
+
+		return false, fmt.Errorf("failed to load job %s: %w", id, err)
+	}
+
+	if !verify(t, job) {
+		http.Error(w, "Invalid access token.", http.StatusForbidden)
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// verify checks the token against the one issued on job creation and the ones issued to repeated requests.
+func verify(t string, job models.Job) bool {
+	if token.Verify(t, job.TokenHash) {
+		return true
+	}
+
+	for _, hash := range job.ReplayTokenHashes {
+		if token.Verify(t, hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boolParam parses an optional boolean query parameter.
+func boolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %w", name, err)
+	}
+
+	return b, nil
+}
+
+// noCache reports whether the client asked to fetch the links instead of serving cached responses.
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+
+	return strings.EqualFold(r.Header.Get("Pragma"), "no-cache")
 }
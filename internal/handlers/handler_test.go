@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 	"testing"
 )
 
@@ -12,11 +13,12 @@ func Test_parseID(t *testing.T) {
 		path     string
 	}
 	validID := rand.Uint64()
+	randomID := "0123456789abcdef0123456789abcdef"
 	basePath := "/api/v1/links"
 	tests := []struct {
 		name    string
 		args    args
-		want    uint64
+		want    string
 		wantErr bool
 	}{
 		{
@@ -25,7 +27,16 @@ func Test_parseID(t *testing.T) {
 				basePath: basePath,
 				path:     fmt.Sprintf("%s/%d", basePath, validID),
 			},
-			want:    validID,
+			want:    strconv.FormatUint(validID, 10),
+			wantErr: false,
+		},
+		{
+			name: "should parse a valid random ID",
+			args: args{
+				basePath: basePath,
+				path:     fmt.Sprintf("%s/%s", basePath, randomID),
+			},
+			want:    randomID,
 			wantErr: false,
 		},
 		{
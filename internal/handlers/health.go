@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+// NewLiveness responds OK as long as the process is able to serve requests.
+func NewLiveness() contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return writeHealth(w, models.Health{Status: models.HealthStatusOK})
+	}
+}
+
+// NewReadiness responds 503 when any of the checks fails.
+func NewReadiness(checker contracts.HealthChecker) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return writeHealth(w, checker.Check())
+	}
+}
+
+func writeHealth(w http.ResponseWriter, health models.Health) error {
+	b, err := json.Marshal(health)
+	if err != nil {
+		http.Error(w, "Failed to encode health status.", http.StatusInternalServerError)
+
+		return fmt.Errorf("failed to JSON encode health status: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if health.Status != models.HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("failed to write response body: %w", err)
+	}
+
+	return nil
+}
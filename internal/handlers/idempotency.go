@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyKeyConflictMsg = "Idempotency key is already used with another request body or parameters."
+)
+
+// newIdempotencyKey hashes the header value scoped by the API key name,
+// so clients with different API keys never share keys.
+// The body hash covers the query parameters too, as they change the job.
+func newIdempotencyKey(value, keyName string, request models.Request, query url.Values) (models.IdempotencyKey, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return models.IdempotencyKey{}, fmt.Errorf("failed to JSON encode request: %w", err)
+	}
+
+	// Encode sorts the parameters by name
+	return models.IdempotencyKey{
+		Hash:     token.Hash(keyName + "\x00" + value),
+		BodyHash: token.Hash(string(body) + "\x00" + query.Encode()),
+	}, nil
+}
+
+// replay responds to a repeated request with the job created by the original one and a new access token.
+// It returns false when the key is not used yet or is expired, so a new job should be created.
+func replay(
+	w http.ResponseWriter,
+	r *http.Request,
+	store contracts.Store,
+	key models.IdempotencyKey,
+	window time.Duration,
+) (bool, error) {
+	stored, err := store.LoadIdempotencyKey(key.Hash)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+		return true, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+
+	if time.Since(stored.CreatedAt) >= window {
+		return false, nil
+	}
+
+	setJobID(r, stored.JobID)
+	if stored.BodyHash != key.BodyHash {
+		http.Error(w, idempotencyKeyConflictMsg, http.StatusConflict)
+
+		return true, fmt.Errorf("idempotency key of job %s is reused with another request body or parameters", stored.JobID)
+	}
+
+	accessToken, tokenHash, err := token.New()
+	if err != nil {
+		http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+		return true, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	addToken := func(job *models.Job) {
+		job.ReplayTokenHashes = append(job.ReplayTokenHashes, tokenHash)
+	}
+	if err := store.UpdateJob(stored.JobID, addToken); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// the job is removed while its key is being removed
+			return false, nil
+		}
+
+		http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+		return true, fmt.Errorf("failed to add access token to job %s: %w", stored.JobID, err)
+	}
+
+	w.Header().Set(accessTokenHeader, accessToken)
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := fmt.Fprint(w, stored.JobID); err != nil {
+		return true, fmt.Errorf("failed to write response body: %w", err)
+	}
+
+	return true, nil
+}
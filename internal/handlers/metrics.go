@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+func NewMetrics(metrics io.WriterTo) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		if _, err := metrics.WriteTo(w); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+
+		return nil
+	}
+}
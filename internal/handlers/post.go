@@ -1,23 +1,35 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 	"yegorov-boris/affise-test-task/internal/contracts"
 	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/logctx"
+	"yegorov-boris/affise-test-task/pkg/token"
+	"yegorov-boris/affise-test-task/pkg/urlnorm"
 )
 
 func NewPost(
-	maxLinksPerIn uint32,
+	logger *slog.Logger,
+	maxLinksPerIn func() uint32,
+	idempotencyWindow time.Duration,
 	state contracts.State,
 	scraper contracts.Scraper,
+	extractor contracts.Extractor,
 	store contracts.Store,
 ) contracts.HandlerWithErr {
 	return func(w http.ResponseWriter, r *http.Request) (e error) {
-		var links models.Input
+		var request models.Request
 
 		callback, hasCallback := r.Context().Value(contracts.ContextKey("callback")).(func())
 		if hasCallback {
@@ -37,19 +49,82 @@ func NewPost(
 
 		defer r.Body.Close()
 
-		if err := json.Unmarshal(data, &links); err != nil {
-			http.Error(w, "Request body should be a JSON encoded array of strings.", http.StatusBadRequest)
+		if err := decodeRequest(data, &request); err != nil {
+			http.Error(w, "Request body should be a JSON encoded array of links or an object with links.", http.StatusBadRequest)
 
 			return fmt.Errorf("failed to decode request body from JSON: %w", err)
 		}
 
-		if err := links.Validate(maxLinksPerIn); err != nil {
+		if err := request.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid assertions: %s.", err), http.StatusBadRequest)
+
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+		links := request.Input()
+		assertions := request.LinkAssertions()
+
+		maxLinks := maxLinksPerIn()
+		apiKey, hasAPIKey := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey)
+		if hasAPIKey {
+			maxLinks = apiKey.LinksPerIn(maxLinks)
+		}
+
+		normalize, err := boolParam(r, "normalize")
+		if err != nil {
+			http.Error(w, "normalize parameter should be a boolean.", http.StatusBadRequest)
+
+			return err
+		}
+		sortQuery, err := boolParam(r, "sort_query")
+		if err != nil {
+			http.Error(w, "sort_query parameter should be a boolean.", http.StatusBadRequest)
+
+			return err
+		}
+
+		extraction, err := extractionParams(r)
+		if err != nil {
+			http.Error(w, err.Error()+".", http.StatusBadRequest)
+
+			return err
+		}
+
+		withDiff, err := boolParam(r, "diff")
+		if err != nil {
+			http.Error(w, "diff parameter should be a boolean.", http.StatusBadRequest)
+
+			return err
+		}
+
+		previousID, ok, err := compareTo(w, r, state, store)
+		if !ok {
+			return err
+		}
+
+		key := func(link string) (string, error) {
+			return link, nil
+		}
+		if normalize || sortQuery {
+			key = func(link string) (string, error) {
+				return urlnorm.Normalize(link, sortQuery)
+			}
+		}
+
+		// duplicates are fetched once and count toward the limits once
+		unique, index, err := links.Unique(key)
+		if err != nil {
+			http.Error(w, "Some strings in the request body are not valid links.", http.StatusBadRequest)
+
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+
+		if err := unique.Validate(maxLinks); err != nil {
 			errMsg := "Some strings in the request body are not valid links."
 			if errors.Is(models.ErrNoLinks, err) {
 				errMsg = "At least 1 link per request should be provided."
 			}
 			if errors.Is(models.ErrTooManyLinks, err) {
-				errMsg = fmt.Sprintf("Maximum %d links per request are allowed", maxLinksPerIn)
+				errMsg = fmt.Sprintf("Maximum %d links per request are allowed", maxLinks)
 			}
 
 			http.Error(w, errMsg, http.StatusBadRequest)
@@ -57,17 +132,213 @@ func NewPost(
 			return fmt.Errorf("invalid request body: %w", err)
 		}
 
-		id, ctx := state.Start()
+		var idempotencyKey models.IdempotencyKey
+		if value := r.Header.Get(idempotencyKeyHeader); len(value) != 0 && idempotencyWindow > 0 {
+			if len(value) > maxIdempotencyKeyLength {
+				errMsg := fmt.Sprintf("%s header should be at most %d characters long.", idempotencyKeyHeader, maxIdempotencyKeyLength)
+				http.Error(w, errMsg, http.StatusBadRequest)
+
+				return fmt.Errorf("invalid %s header: too long", idempotencyKeyHeader)
+			}
+
+			keyName := ""
+			if hasAPIKey {
+				keyName = apiKey.KeyName()
+			}
+			if idempotencyKey, err = newIdempotencyKey(value, keyName, request, r.URL.Query()); err != nil {
+				http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+				return err
+			}
+
+			if replayed, err := replay(w, r, store, idempotencyKey, idempotencyWindow); replayed {
+				if err == nil && hasCallback {
+					callback()
+				}
+
+				return err
+			}
+		}
+
+		var reserved uint32
+		if hasAPIKey {
+			if !apiKey.ReserveLinks(uint32(len(unique))) {
+				http.Error(w, "Daily links quota of the API key is exceeded.", http.StatusTooManyRequests)
+
+				return fmt.Errorf("daily links quota of API key %q is exceeded", apiKey.KeyName())
+			}
+			reserved = uint32(len(unique))
+
+			// the quota is used only by the started jobs,
+			// e.g. not when a concurrent request with the same idempotency key wins
+			defer func() {
+				if reserved != 0 {
+					apiKey.ReleaseLinks(reserved)
+				}
+			}()
+		}
+
+		id, ctx, err := state.Start(r.Context())
+		if err != nil {
+			if errors.Is(err, models.ErrShuttingDown) {
+				http.Error(w, "Sorry, the service is shutting down. Please, try again a bit later.", http.StatusServiceUnavailable)
+
+				return fmt.Errorf("failed to start processing: %w", err)
+			}
+
+			http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to start processing: %w", err)
+		}
+		setJobID(r, id)
+
+		accessToken, tokenHash, err := token.New()
+		if err != nil {
+			state.Finish(id)
+			http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to generate access token: %w", err)
+		}
+
+		job := models.Job{
+			ID:        id,
+			TokenHash: tokenHash,
+			CreatedAt: time.Now(),
+		}
+		if hasAPIKey {
+			job.KeyName = apiKey.KeyName()
+		}
+		if requestID, ok := r.Context().Value(contracts.ContextKey("requestID")).(string); ok {
+			job.RequestID = requestID
+		}
+		if len(idempotencyKey.Hash) != 0 {
+			job.IdempotencyKeyHash = idempotencyKey.Hash
+		}
+		job.PreviousJobID = previousID
+		if err := store.SaveJob(job); err != nil {
+			state.Finish(id)
+			http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to save job: %w", err)
+		}
+
+		if len(idempotencyKey.Hash) != 0 {
+			idempotencyKey.JobID = id
+			idempotencyKey.CreatedAt = job.CreatedAt
+			if err := store.SaveIdempotencyKey(idempotencyKey, job.CreatedAt.Add(-idempotencyWindow)); err != nil {
+				state.Finish(id)
+				if _, errRemove := store.Remove(id); errRemove != nil {
+					err = errors.Join(err, errRemove)
+				}
+
+				if !errors.Is(err, fs.ErrExist) {
+					http.Error(w, "Failed to start processing.", http.StatusInternalServerError)
+
+					return fmt.Errorf("failed to save idempotency key: %w", err)
+				}
+
+				// a concurrent request with the same key has created its job first
+				if replayed, err := replay(w, r, store, idempotencyKey, idempotencyWindow); replayed {
+					if err == nil && hasCallback {
+						callback()
+					}
+
+					return err
+				}
+
+				http.Error(w, "A request with the same idempotency key is in progress.", http.StatusConflict)
+
+				return fmt.Errorf("failed to save idempotency key: %w", err)
+			}
+		}
+
+		reserved = 0
+		w.Header().Set(accessTokenHeader, accessToken)
 		w.WriteHeader(http.StatusAccepted)
-		if _, err := fmt.Fprintf(w, "%d", id); err != nil {
+		if _, err := fmt.Fprint(w, id); err != nil {
 			state.Finish(id)
+			if _, errRemove := store.Remove(id); errRemove != nil {
+				err = errors.Join(err, errRemove)
+			}
 
 			return fmt.Errorf("failed to write response body: %w", err)
 		}
 
+		ctx = logctx.With(ctx, slog.String("job_id", id))
+		if noCache(r) {
+			ctx = context.WithValue(ctx, contracts.ContextKey("noCache"), true)
+		}
+		hasAssertions := false
+		for _, a := range assertions {
+			hasAssertions = hasAssertions || a != nil
+		}
+
+		var check models.Check
+		if hasAssertions && request.FailFast {
+			check = func(u int, output models.Output) error {
+				for i, j := range index {
+					if j != u || assertions[i] == nil {
+						continue
+					}
+					if verdict := assertions[i].Check(output); !verdict.Passed {
+						return errors.New(strings.Join(verdict.Reasons, ", "))
+					}
+				}
+
+				return nil
+			}
+		}
+
 		go func() {
-			outputs, errMsg := scraper.Scrap(ctx, links)
-			store.Save(id, outputs, errMsg)
+			outputs, errMsg := scraper.Scrap(ctx, unique, check)
+			passed := len(errMsg) == 0
+			if len(errMsg) == 0 {
+				// verdicts are made before the body is dropped by extraction
+				verdicts := make([]*models.Verdict, len(links))
+				for i, a := range assertions {
+					if a != nil {
+						verdict := a.Check(outputs[index[i]])
+						verdicts[i] = &verdict
+						passed = passed && verdict.Passed
+					}
+				}
+
+				for i := range outputs {
+					outputs[i].BodyHash = models.ContentHash(outputs[i].Body)
+				}
+
+				// changes are detected before the body is dropped by extraction too,
+				// links without previous outputs (e.g. of a failed or removed job) are new
+				var changes []*models.Change
+				if len(previousID) != 0 {
+					previous, _ := loadOutputs(store, previousID)
+					changes = detectChanges(links, outputs, index, previous, withDiff)
+				}
+
+				for i := range outputs {
+					outputs[i] = extractor.Extract(outputs[i], extraction)
+				}
+				outputs = links.Expand(outputs, index)
+				for i := range outputs {
+					outputs[i].Verdict = verdicts[i]
+					if changes != nil {
+						outputs[i].Change = changes[i]
+					}
+				}
+			}
+			if len(errMsg) != 0 && errors.Is(context.Cause(ctx), models.ErrInterrupted) {
+				errMsg = "Requests interrupted by shutdown."
+			}
+			if hasAssertions {
+				setPassed := func(job *models.Job) {
+					job.AssertionsPassed = &passed
+				}
+				// the verdicts are stored in the outputs anyway
+				if err := store.UpdateJob(id, setPassed); err != nil {
+					logger.ErrorContext(ctx, "failed to save the assertions result", slog.Any("error", err))
+				}
+			}
+			store.Save(ctx, id, outputs, errMsg)
 			state.Finish(id)
 			if hasCallback {
 				callback()
@@ -77,3 +348,51 @@ func NewPost(
 		return nil
 	}
 }
+
+// extractionParams parses the extractors of a job:
+// ?extract=title,meta,links,text&select=items.0.name&select=total&drop_body=true
+func extractionParams(r *http.Request) (models.Extraction, error) {
+	var extraction models.Extraction
+
+	query := r.URL.Query()
+	for _, value := range query["extract"] {
+		for _, name := range strings.Split(value, ",") {
+			switch strings.TrimSpace(name) {
+			case "title":
+				extraction.Title = true
+			case "meta":
+				extraction.Meta = true
+			case "links":
+				extraction.Links = true
+			case "text":
+				extraction.Text = true
+			default:
+				return extraction, fmt.Errorf("extract parameter should be a list of title, meta, links and text, got %q", name)
+			}
+		}
+	}
+
+	for _, path := range query["select"] {
+		if len(path) == 0 {
+			return extraction, errors.New("select parameter should not be empty")
+		}
+		extraction.JSONPaths = append(extraction.JSONPaths, path)
+	}
+
+	dropBody, err := boolParam(r, "drop_body")
+	if err != nil {
+		return extraction, errors.New("drop_body parameter should be a boolean")
+	}
+	extraction.DropBody = dropBody
+
+	return extraction, nil
+}
+
+// decodeRequest accepts either an array of links or an object with links and assertions.
+func decodeRequest(data []byte, request *models.Request) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		return json.Unmarshal(data, request)
+	}
+
+	return json.Unmarshal(data, &request.Links)
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/internal/services/apikeys"
+	"yegorov-boris/affise-test-task/internal/services/extractor"
+	"yegorov-boris/affise-test-task/internal/services/progress"
+	"yegorov-boris/affise-test-task/internal/services/store"
+	"yegorov-boris/affise-test-task/pkg/semaphore"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+type (
+	// scraperMock keeps the links of every job
+	scraperMock struct {
+		inputs []models.Input
+	}
+
+	// racingStore does not find the idempotency key once,
+	// as if a concurrent request with the same key saved it after the check
+	racingStore struct {
+		*store.Store
+		race bool
+	}
+)
+
+func (s *scraperMock) Scrap(ctx context.Context, input models.Input, check models.Check) ([]models.Output, string) {
+	s.inputs = append(s.inputs, input)
+	outputs := make([]models.Output, len(input))
+	for i, link := range input {
+		outputs[i] = models.Output{URL: link, StatusCode: http.StatusOK, Body: "<title>page</title>"}
+	}
+
+	return outputs, ""
+}
+
+func (s *racingStore) LoadIdempotencyKey(hash string) (models.IdempotencyKey, error) {
+	if s.race {
+		s.race = false
+
+		return models.IdempotencyKey{}, fs.ErrNotExist
+	}
+
+	return s.Store.LoadIdempotencyKey(hash)
+}
+
+func TestNewPost_idempotency(t *testing.T) {
+	storePath := "./store/"
+	body := `["https://example.com"]`
+	tests := []struct {
+		name         string
+		query        string
+		body         string
+		wantStatus   int
+		wantReplayed bool
+	}{
+		{
+			name:       "should create a job",
+			query:      "extract=title&select=a&select=b",
+			body:       body,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:         "should replay the job of the same request",
+			query:        "select=a&extract=title&select=b",
+			body:         body,
+			wantStatus:   http.StatusAccepted,
+			wantReplayed: true,
+		},
+		{
+			name:       "should reject the same key with other parameters",
+			query:      "extract=meta&select=a&select=b",
+			body:       body,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "should reject the same key without parameters",
+			body:       body,
+			wantStatus: http.StatusConflict,
+		},
+	}
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	s := store.New(slog.Default(), storePath)
+	handler := NewPost(
+		slog.Default(),
+		func() uint32 { return 10 },
+		time.Hour,
+		state,
+		&scraperMock{},
+		extractor.New(),
+		s,
+	)
+
+	var jobID string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/links?"+tt.query, strings.NewReader(tt.body))
+			r.Header.Set(idempotencyKeyHeader, "key")
+			w := httptest.NewRecorder()
+
+			_ = handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("NewPost() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+				return
+			}
+			if replayed := w.Header().Get(idempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("NewPost() replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+			if len(jobID) == 0 {
+				jobID = w.Body.String()
+			}
+			if w.Body.String() != jobID {
+				t.Errorf("NewPost() job ID = %q, want %q", w.Body.String(), jobID)
+			}
+		})
+	}
+
+	if err := state.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestNewPost_idempotencyRace(t *testing.T) {
+	storePath := "./store/"
+	tests := []struct {
+		name           string
+		idempotencyKey string
+		body           string
+		race           bool
+		wantStatus     int
+		wantReplayed   bool
+	}{
+		{
+			name:           "should create a job",
+			idempotencyKey: "key",
+			body:           `["https://example.com/1"]`,
+			wantStatus:     http.StatusAccepted,
+		},
+		{
+			name:           "should reject the same key with another body",
+			idempotencyKey: "key",
+			body:           `["https://example.com/2"]`,
+			wantStatus:     http.StatusConflict,
+		},
+		{
+			name:           "should replay the job of a concurrent request with the same key",
+			idempotencyKey: "key",
+			body:           `["https://example.com/1"]`,
+			race:           true,
+			wantStatus:     http.StatusAccepted,
+			wantReplayed:   true,
+		},
+		{
+			name:           "should reject a concurrent request with the same key and another body",
+			idempotencyKey: "key",
+			body:           `["https://example.com/2"]`,
+			race:           true,
+			wantStatus:     http.StatusConflict,
+		},
+		{
+			name:       "should not use the quota for the concurrent requests which did not create jobs",
+			body:       `["https://example.com/3"]`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "should exceed the quota",
+			body:       `["https://example.com/4"]`,
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	s := &racingStore{Store: store.New(slog.Default(), storePath)}
+	apiKey := &apikeys.Key{Name: "a", DailyLinkQuota: 2}
+	handler := NewPost(
+		slog.Default(),
+		func() uint32 { return 10 },
+		time.Hour,
+		state,
+		&scraperMock{},
+		extractor.New(),
+		s,
+	)
+
+	var jobID string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(tt.body))
+			r = r.WithContext(context.WithValue(r.Context(), contracts.ContextKey("apiKey"), contracts.APIKey(apiKey)))
+			if len(tt.idempotencyKey) != 0 {
+				r.Header.Set(idempotencyKeyHeader, tt.idempotencyKey)
+			}
+			w := httptest.NewRecorder()
+			s.race = tt.race
+
+			_ = handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("NewPost() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+				return
+			}
+			if replayed := w.Header().Get(idempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("NewPost() replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if len(jobID) == 0 {
+				jobID = w.Body.String()
+			}
+			if tt.wantReplayed && w.Body.String() != jobID {
+				t.Errorf("NewPost() job ID = %q, want %q", w.Body.String(), jobID)
+			}
+		})
+	}
+
+	if err := state.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestNewPost_compareToRejected(t *testing.T) {
+	storePath := "./store/"
+	finishedID := "100"
+
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	s := store.New(slog.Default(), storePath)
+	accessToken, tokenHash, err := token.New()
+	if err != nil {
+		t.Errorf("token.New() error = %v", err)
+		return
+	}
+	inProgressID, _, err := state.Start(context.Background())
+	if err != nil {
+		t.Errorf("Start() error = %v", err)
+		return
+	}
+	for _, id := range []string{inProgressID, finishedID} {
+		if err := s.SaveJob(models.Job{ID: id, TokenHash: tokenHash, CreatedAt: time.Now()}); err != nil {
+			t.Errorf("SaveJob() error = %v", err)
+			return
+		}
+	}
+	handler := NewPost(
+		slog.Default(),
+		func() uint32 { return 10 },
+		time.Hour,
+		state,
+		&scraperMock{},
+		extractor.New(),
+		s,
+	)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{
+			name:       "should release the slot when the access token is missing",
+			query:      "compare_to=" + finishedID,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should release the slot when the job is not found",
+			query:      "compare_to=101&compare_token=" + accessToken,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should release the slot when the access token is invalid",
+			query:      "compare_to=" + finishedID + "&compare_token=invalid",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "should release the slot when the job is in progress",
+			query:      "compare_to=" + inProgressID + "&compare_token=" + accessToken,
+			wantStatus: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := semaphore.New(1)
+			if !slots.TryAcquire() {
+				t.Errorf("TryAcquire() = false, want true")
+				return
+			}
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/links?"+tt.query, strings.NewReader(`["https://example.com"]`))
+			r = r.WithContext(context.WithValue(r.Context(), contracts.ContextKey("callback"), slots.Release))
+			w := httptest.NewRecorder()
+
+			if err := handler(w, r); err == nil {
+				t.Errorf("NewPost() error = nil, want an error")
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("NewPost() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if slots.Len() != 0 {
+				t.Errorf("slots in use = %d, want 0", slots.Len())
+			}
+		})
+	}
+
+	state.Finish(inProgressID)
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestNewPost_duplicates(t *testing.T) {
+	storePath := "./store/"
+	links := models.Input{
+		"https://example.com/a",
+		"HTTPS://Example.com:443/a",
+		"https://example.com/b",
+		"https://example.com/./a",
+	}
+	body, _ := json.Marshal(links)
+
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	s := store.New(slog.Default(), storePath)
+	scraper := &scraperMock{}
+	handler := NewPost(
+		slog.Default(),
+		func() uint32 { return 2 },
+		time.Hour,
+		state,
+		scraper,
+		extractor.New(),
+		s,
+	)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/links?normalize=true", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	if err := handler(w, r); err != nil {
+		t.Errorf("NewPost() error = %v", err)
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("NewPost() status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	if err := state.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+
+	wantInputs := []models.Input{{"https://example.com/a", "https://example.com/b"}}
+	if !reflect.DeepEqual(scraper.inputs, wantInputs) {
+		t.Errorf("Scrap() inputs = %v, want %v", scraper.inputs, wantInputs)
+	}
+	outputs, err := loadOutputs(s, w.Body.String())
+	if err != nil {
+		t.Errorf("loadOutputs() error = %v", err)
+	}
+	if len(outputs) != len(links) {
+		t.Errorf("NewPost() outputs = %d, want %d", len(outputs), len(links))
+	}
+	for i := range outputs {
+		if outputs[i].URL != links[i] {
+			t.Errorf("NewPost() output %d URL = %q, want %q", i, outputs[i].URL, links[i])
+		}
+	}
+
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestNewPost_shuttingDown(t *testing.T) {
+	storePath := "./store/"
+
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	state, err := progress.New(storePath, false)
+	if err != nil {
+		t.Errorf("progress.New() error = %v", err)
+		return
+	}
+	state.Close()
+	handler := NewPost(
+		slog.Default(),
+		func() uint32 { return 10 },
+		time.Hour,
+		state,
+		&scraperMock{},
+		extractor.New(),
+		store.New(slog.Default(), storePath),
+	)
+	slots := semaphore.New(1)
+	if !slots.TryAcquire() {
+		t.Errorf("TryAcquire() = false, want true")
+		return
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`["https://example.com"]`))
+	r = r.WithContext(context.WithValue(r.Context(), contracts.ContextKey("callback"), slots.Release))
+	w := httptest.NewRecorder()
+
+	if err := handler(w, r); !errors.Is(err, models.ErrShuttingDown) {
+		t.Errorf("NewPost() error = %v, want %v", err, models.ErrShuttingDown)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("NewPost() status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if slots.Len() != 0 {
+		t.Errorf("slots in use = %d, want 0", slots.Len())
+	}
+
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+func NewPurge(store contracts.Store) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		count, err := store.Purge()
+		if err != nil {
+			http.Error(w, "Failed to purge outputs.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to purge outputs: %w", err)
+		}
+
+		if _, err := fmt.Fprintf(w, "%d", count); err != nil {
+			return fmt.Errorf("failed to write response body: %w", err)
+		}
+
+		return nil
+	}
+}
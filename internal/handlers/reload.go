@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+// NewReload reloads the config and responds with the applied changes.
+func NewReload(reload func() ([]models.ConfigChange, error)) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		changes, err := reload()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload config: %s.", err), http.StatusUnprocessableEntity)
+
+			return fmt.Errorf("failed to reload config: %w", err)
+		}
+
+		if changes == nil {
+			changes = []models.ConfigChange{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(changes); err != nil {
+			return fmt.Errorf("failed to write response body: %w", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+// scheduleOptions are the query parameters of POST links allowed in the options of schedules.
+var scheduleOptions = map[string]struct{}{
+	"normalize":  {},
+	"sort_query": {},
+	"extract":    {},
+	"select":     {},
+	"drop_body":  {},
+	"diff":       {},
+}
+
+func NewScheduleCreate(maxLinksPerIn func() uint32, scheduler contracts.Scheduler) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var schedule models.Schedule
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		defer r.Body.Close()
+
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			http.Error(w, "Request body should be a JSON encoded schedule.", http.StatusBadRequest)
+
+			return fmt.Errorf("failed to decode request body from JSON: %w", err)
+		}
+
+		if err := schedule.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid schedule: %s.", err), http.StatusBadRequest)
+
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+
+		maxLinks := maxLinksPerIn()
+		apiKey, hasAPIKey := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey)
+		if hasAPIKey {
+			maxLinks = apiKey.LinksPerIn(maxLinks)
+			schedule.KeyName = apiKey.KeyName()
+		}
+
+		links := schedule.Input()
+		if err := links.Validate(maxLinks); err != nil {
+			errMsg := "Some strings in the request body are not valid links."
+			if errors.Is(models.ErrNoLinks, err) {
+				errMsg = "At least 1 link per schedule should be provided."
+			}
+			if errors.Is(models.ErrTooManyLinks, err) {
+				errMsg = fmt.Sprintf("Maximum %d links per schedule are allowed", maxLinks)
+			}
+
+			http.Error(w, errMsg, http.StatusBadRequest)
+
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+
+		if err := validateScheduleOptions(schedule.Options); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid options: %s.", err), http.StatusBadRequest)
+
+			return fmt.Errorf("invalid schedule options: %w", err)
+		}
+
+		accessToken, tokenHash, err := token.New()
+		if err != nil {
+			http.Error(w, "Failed to create schedule.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to generate access token: %w", err)
+		}
+		schedule.TokenHash = tokenHash
+
+		created, err := scheduler.Create(schedule)
+		if err != nil {
+			http.Error(w, "Failed to create schedule.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to create schedule: %w", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(accessTokenHeader, accessToken)
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(created); err != nil {
+			return fmt.Errorf("failed to write response body: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// NewScheduleList returns the schedules created with the API key of the request, or all of them without API keys.
+func NewScheduleList(scheduler contracts.Scheduler) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		apiKey, hasAPIKey := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey)
+
+		schedules := make([]models.Schedule, 0)
+		for _, schedule := range scheduler.List() {
+			if hasAPIKey && schedule.KeyName != apiKey.KeyName() {
+				continue
+			}
+			schedules = append(schedules, schedule)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(schedules); err != nil {
+			return fmt.Errorf("failed to write response body: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func NewScheduleDelete(basePath string, scheduler contracts.Scheduler) contracts.HandlerWithErr {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := parseID(basePath, r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+			return fmt.Errorf("invalid ID: %w", err)
+		}
+
+		t := r.Header.Get(accessTokenHeader)
+		if len(t) == 0 {
+			t = r.URL.Query().Get(accessTokenParam)
+		}
+
+		if len(t) == 0 {
+			http.Error(w, "Access token is required.", http.StatusUnauthorized)
+
+			return nil
+		}
+
+		schedule, ok := scheduler.Get(id)
+		if !ok {
+			http.Error(w, "Schedule not found by ID", http.StatusNotFound)
+
+			return nil
+		}
+
+		if !token.Verify(t, schedule.TokenHash) {
+			http.Error(w, "Invalid access token.", http.StatusForbidden)
+
+			return nil
+		}
+
+		removed, err := scheduler.Delete(id)
+		if err != nil {
+			http.Error(w, "Failed to remove schedule.", http.StatusInternalServerError)
+
+			return fmt.Errorf("failed to remove schedule %s: %w", id, err)
+		}
+
+		if !removed {
+			http.Error(w, "Schedule not found by ID", http.StatusNotFound)
+
+			return nil
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return nil
+	}
+}
+
+// validateScheduleOptions checks the options the same way POST links checks its query.
+func validateScheduleOptions(options string) error {
+	query, err := url.ParseQuery(options)
+	if err != nil {
+		return fmt.Errorf("options should be a URL query: %w", err)
+	}
+
+	for name := range query {
+		if _, ok := scheduleOptions[name]; !ok {
+			return fmt.Errorf("unknown option %q", name)
+		}
+	}
+
+	r := &http.Request{URL: &url.URL{RawQuery: options}}
+	for _, name := range []string{"normalize", "sort_query", "diff"} {
+		if _, err := boolParam(r, name); err != nil {
+			return err
+		}
+	}
+
+	_, err = extractionParams(r)
+
+	return err
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+}
+
+// NewAccessLog logs every request with its outcome as structured attributes.
+// Handlers report the job ID the request relates to via the "jobID" context value.
+func NewAccessLog(logger *slog.Logger, proxies TrustedProxies, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		jobID := new(string)
+		rec := &responseRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+
+		inner.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), contracts.ContextKey("jobID"), jobID)))
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", proxies.ClientIP(r)),
+		}
+		if len(*jobID) != 0 {
+			attrs = append(attrs, slog.String("job_id", *jobID))
+		}
+
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "access", attrs...)
+	})
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/pkg/logctx"
+)
+
+// NewAuth checks the API key passed either as a bearer token or in the X-API-Key header
+// and puts it to the request context.
+func NewAuth(keys contracts.APIKeys, endpoint string, inner contracts.Handler) contracts.Handler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); len(key) == 0 && strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if len(key) == 0 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "API key is required.", http.StatusUnauthorized)
+			return
+		}
+
+		apiKey, ok := keys.Lookup(key)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Invalid API key.", http.StatusUnauthorized)
+			return
+		}
+
+		if !apiKey.Allows(endpoint) {
+			http.Error(w, "The API key is not allowed to access this endpoint.", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contracts.ContextKey("apiKey"), apiKey)
+		ctx = logctx.With(ctx, slog.String("api_key", apiKey.KeyName()))
+		inner(w, r.Clone(ctx))
+	}
+}
+
+// NewKeyJobsLimiter limits the number of concurrent jobs per API key.
+// The slot is released when the job finishes.
+func NewKeyJobsLimiter(inner contracts.Handler) contracts.Handler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey, ok := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey)
+		if !ok {
+			inner(w, r)
+			return
+		}
+
+		if !apiKey.StartJob() {
+			http.Error(w, "Concurrent requests limit of the API key is exceeded. Please, try again a bit later.", http.StatusTooManyRequests)
+			return
+		}
+
+		inner(w, withCallback(r, apiKey.FinishJob))
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+// withCallback adds f to the callback called by the handler when it finishes processing a request.
+// Callbacks registered by outer middlewares are called after f.
+func withCallback(r *http.Request, f func()) *http.Request {
+	next := func() {}
+	if callback, ok := r.Context().Value(contracts.ContextKey("callback")).(func()); ok {
+		next = callback
+	}
+
+	return r.Clone(context.WithValue(r.Context(), contracts.ContextKey("callback"), func() {
+		f()
+		next()
+	}))
+}
+
+// release calls the callbacks registered by outer middlewares
+// when a request is rejected before reaching the handler.
+func release(r *http.Request) {
+	if callback, ok := r.Context().Value(contracts.ContextKey("callback")).(func()); ok {
+		callback()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Errorf("NewTrustedProxies() error = %v", err)
+		return
+	}
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{
+			name:         "should ignore X-Forwarded-For from an untrusted address",
+			remoteAddr:   "1.2.3.4:1234",
+			forwardedFor: "5.6.7.8",
+			want:         "1.2.3.4",
+		},
+		{
+			name:         "should take the first untrusted address from the right",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "9.9.9.9, 5.6.7.8, 192.168.1.1",
+			want:         "5.6.7.8",
+		},
+		{
+			name:         "should take the leftmost address when all the proxies are trusted",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "10.0.0.2, 192.168.1.1",
+			want:         "10.0.0.2",
+		},
+		{
+			name:       "should take the remote address of a trusted proxy without X-Forwarded-For",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if len(tt.forwardedFor) != 0 {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := proxies.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
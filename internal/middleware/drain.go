@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+// NewDrain rejects new jobs once graceful shutdown has started.
+func NewDrain(draining *atomic.Bool, inner contracts.Handler) contracts.Handler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			release(r)
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Sorry, the service is shutting down. Please, try again a bit later.", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		inner(w, r)
+	}
+}
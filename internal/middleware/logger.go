@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"fmt"
 	"log/slog"
 	"net/http"
 	"yegorov-boris/affise-test-task/internal/contracts"
@@ -10,7 +9,14 @@ import (
 func NewLogger(logger *slog.Logger, inner contracts.HandlerWithErr) contracts.Handler {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := inner(w, r); err != nil {
-			logger.Error(fmt.Sprintf("failed to handle %s %s: %s", r.Method, r.URL, err))
+			logger.ErrorContext(
+				r.Context(),
+				"failed to handle request",
+				slog.String("method", r.Method),
+				// the query may carry access tokens
+				slog.String("path", r.URL.Path),
+				slog.Any("error", err),
+			)
 		}
 	}
 }
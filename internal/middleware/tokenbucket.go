@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+)
+
+// NewTokenBucket limits requests rate per client (see contracts.ClientKeyFunc)
+// and reports the limits in the RateLimit-* headers.
+func NewTokenBucket(
+	limiter contracts.TokenBucket,
+	clientKey contracts.ClientKeyFunc,
+	inner contracts.Handler,
+) contracts.Handler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := limiter.Allow(clientKey(r))
+
+		w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d", limit.Limit))
+		w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d", limit.Remaining))
+		w.Header().Set("RateLimit-Reset", fmt.Sprintf("%d", seconds(limit.Reset)))
+
+		if !limit.Allowed {
+			release(r)
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds(limit.RetryAfter)))
+			http.Error(w, "Sorry, too many requests. Please, try again a bit later.", http.StatusTooManyRequests)
+			return
+		}
+
+		inner(w, r)
+	}
+}
+
+// ClientKeyByIP identifies clients by IP address.
+func ClientKeyByIP(proxies TrustedProxies) contracts.ClientKeyFunc {
+	return func(r *http.Request) string {
+		return fmt.Sprintf("ip:%s", proxies.ClientIP(r))
+	}
+}
+
+// ClientKeyByAPIKey identifies clients by API key name falling back to IP address.
+func ClientKeyByAPIKey(proxies TrustedProxies) contracts.ClientKeyFunc {
+	byIP := ClientKeyByIP(proxies)
+
+	return func(r *http.Request) string {
+		if apiKey, ok := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey); ok {
+			return fmt.Sprintf("key:%s", apiKey.KeyName())
+		}
+
+		return byIP(r)
+	}
+}
+
+// ClientKeyBySchedule identifies the jobs of schedules by API key name falling back to schedule ID.
+func ClientKeyBySchedule() contracts.ClientKeyFunc {
+	return func(r *http.Request) string {
+		if apiKey, ok := r.Context().Value(contracts.ContextKey("apiKey")).(contracts.APIKey); ok {
+			return fmt.Sprintf("key:%s", apiKey.KeyName())
+		}
+
+		id, _ := r.Context().Value(contracts.ContextKey("scheduleID")).(string)
+
+		return fmt.Sprintf("schedule:%s", id)
+	}
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
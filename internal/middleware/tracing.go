@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/pkg/tracing"
+)
+
+// NewTracing starts a server span per request continuing the trace passed in the traceparent header.
+func NewTracing(tracer *tracing.Tracer, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", r.Method, r.URL.Path), tracing.KindServer)
+		defer span.End()
+
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		if requestID, ok := r.Context().Value(contracts.ContextKey("requestID")).(string); ok {
+			span.SetAttribute("request_id", requestID)
+		}
+
+		rec := &responseRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		inner.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttribute("http.response.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(rec.status))
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is the object form of the POST body, the array form has links only.
+type Request struct {
+	Links []Link `json:"links"`
+	// Assertions apply to the links without their own ones
+	Assertions *Assertions `json:"assertions,omitempty"`
+	// FailFast stops the job on the first failed assertion
+	FailFast bool `json:"fail_fast,omitempty"`
+}
+
+// Link is either a URL string or an object with the URL and its own assertions.
+type Link struct {
+	URL        string      `json:"url"`
+	Assertions *Assertions `json:"assertions,omitempty"`
+}
+
+// Assertions are the expectations of a link output, all of them should be met.
+type Assertions struct {
+	Status       []StatusRange     `json:"status,omitempty"`
+	BodyContains string            `json:"body_contains,omitempty"`
+	BodyRegex    string            `json:"body_regex,omitempty"`
+	MaxLatency   string            `json:"max_latency,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"` // header values should contain the strings
+
+	bodyRegex  *regexp.Regexp
+	maxLatency time.Duration
+}
+
+// StatusRange is a status code (200), a class (2xx) or a range (200-299).
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// Verdict tells whether the output passed the assertions and why not.
+type Verdict struct {
+	Passed  bool     `json:"passed"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Check is called with the output of every link of a job, the job fails fast when it returns an error.
+type Check func(i int, output Output) error
+
+var ErrInvalidLink = errors.New("link should be a string or an object with url")
+
+func (r *Request) Input() Input {
+	input := make(Input, 0, len(r.Links))
+	for _, link := range r.Links {
+		input = append(input, link.URL)
+	}
+
+	return input
+}
+
+// LinkAssertions returns the assertions of every link, nil when a link has none.
+func (r *Request) LinkAssertions() []*Assertions {
+	assertions := make([]*Assertions, 0, len(r.Links))
+	for _, link := range r.Links {
+		if link.Assertions != nil {
+			assertions = append(assertions, link.Assertions)
+		} else {
+			assertions = append(assertions, r.Assertions)
+		}
+	}
+
+	return assertions
+}
+
+// Validate checks and compiles the assertions.
+func (r *Request) Validate() error {
+	if r.Assertions != nil {
+		if err := r.Assertions.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, link := range r.Links {
+		if link.Assertions == nil {
+			continue
+		}
+		if err := link.Assertions.Validate(); err != nil {
+			return fmt.Errorf("invalid assertions of %s: %w", link.URL, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *Link) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &l.URL); err == nil {
+		return nil
+	}
+
+	type link Link
+	if err := json.Unmarshal(b, (*link)(l)); err != nil || len(l.URL) == 0 {
+		return ErrInvalidLink
+	}
+
+	return nil
+}
+
+func (s *StatusRange) UnmarshalJSON(b []byte) error {
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		s.Min, s.Max = code, code
+
+		return s.validate()
+	}
+
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return fmt.Errorf("status should be a number or a string: %w", err)
+	}
+
+	if len(value) == 3 && strings.HasSuffix(strings.ToLower(value), "xx") {
+		class, err := strconv.Atoi(value[:1])
+		if err != nil {
+			return fmt.Errorf("invalid status class %q", value)
+		}
+		s.Min, s.Max = class*100, class*100+99
+
+		return s.validate()
+	}
+
+	low, high, isRange := strings.Cut(value, "-")
+	if !isRange {
+		high = low
+	}
+
+	var err error
+	if s.Min, err = strconv.Atoi(strings.TrimSpace(low)); err != nil {
+		return fmt.Errorf("invalid status %q", value)
+	}
+	if s.Max, err = strconv.Atoi(strings.TrimSpace(high)); err != nil {
+		return fmt.Errorf("invalid status %q", value)
+	}
+
+	return s.validate()
+}
+
+func (s StatusRange) MarshalJSON() ([]byte, error) {
+	if s.Min == s.Max {
+		return json.Marshal(s.Min)
+	}
+
+	return json.Marshal(fmt.Sprintf("%d-%d", s.Min, s.Max))
+}
+
+func (s StatusRange) String() string {
+	if s.Min == s.Max {
+		return strconv.Itoa(s.Min)
+	}
+
+	return fmt.Sprintf("%d-%d", s.Min, s.Max)
+}
+
+func (s *StatusRange) validate() error {
+	if s.Min < 100 || s.Max > 599 || s.Min > s.Max {
+		return fmt.Errorf("invalid status range %s", s)
+	}
+
+	return nil
+}
+
+// Validate checks and compiles the assertions.
+func (a *Assertions) Validate() error {
+	if len(a.BodyRegex) != 0 {
+		re, err := regexp.Compile(a.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid body_regex: %w", err)
+		}
+		a.bodyRegex = re
+	}
+
+	if len(a.MaxLatency) != 0 {
+		d, err := time.ParseDuration(a.MaxLatency)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("max_latency should be a positive duration like 500ms, got %q", a.MaxLatency)
+		}
+		a.maxLatency = d
+	}
+
+	return nil
+}
+
+// Check returns the verdict on the output. The assertions should be validated first.
+func (a *Assertions) Check(output Output) Verdict {
+	var reasons []string
+
+	if len(a.Status) != 0 {
+		matched := false
+		for _, s := range a.Status {
+			if output.StatusCode >= s.Min && output.StatusCode <= s.Max {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			reasons = append(reasons, fmt.Sprintf("status %d is not expected", output.StatusCode))
+		}
+	}
+
+	if len(a.BodyContains) != 0 && !strings.Contains(output.Body, a.BodyContains) {
+		reasons = append(reasons, fmt.Sprintf("body does not contain %q", a.BodyContains))
+	}
+
+	if a.bodyRegex != nil && !a.bodyRegex.MatchString(output.Body) {
+		reasons = append(reasons, fmt.Sprintf("body does not match %q", a.BodyRegex))
+	}
+
+	if a.maxLatency > 0 && output.Latency > a.maxLatency {
+		reasons = append(reasons, fmt.Sprintf("latency %s exceeds %s", output.Latency.Round(time.Millisecond), a.maxLatency))
+	}
+
+	names := make([]string, 0, len(a.Headers))
+	for name := range a.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		expected := a.Headers[name]
+		values, ok := output.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("header %s is missing", name))
+			continue
+		}
+
+		found := false
+		for _, v := range values {
+			if strings.Contains(v, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reasons = append(reasons, fmt.Sprintf("header %s does not contain %q", name, expected))
+		}
+	}
+
+	return Verdict{
+		Passed:  len(reasons) == 0,
+		Reasons: reasons,
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRequest_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Request
+		wantErr bool
+	}{
+		{
+			name: "should decode links as strings and objects",
+			body: `{"links": ["https://a.com", {"url": "https://b.com", "assertions": {"status": ["2xx", 404, "500-503"]}}], "fail_fast": true}`,
+			want: Request{
+				Links: []Link{
+					{URL: "https://a.com"},
+					{URL: "https://b.com", Assertions: &Assertions{Status: []StatusRange{{200, 299}, {404, 404}, {500, 503}}}},
+				},
+				FailFast: true,
+			},
+		},
+		{
+			name:    "should fail for an invalid status",
+			body:    `{"links": ["https://a.com"], "assertions": {"status": ["6xx"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for a link object without url",
+			body:    `{"links": [{"assertions": {}}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Request
+			err := json.Unmarshal([]byte(tt.body), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertions_Check(t *testing.T) {
+	output := Output{
+		StatusCode: http.StatusInternalServerError,
+		Body:       "Internal error #42",
+		Header:     http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Latency:    700 * time.Millisecond,
+	}
+	tests := []struct {
+		name       string
+		assertions Assertions
+		want       Verdict
+	}{
+		{
+			name: "should pass when all assertions are met",
+			assertions: Assertions{
+				Status:       []StatusRange{{200, 299}, {500, 599}},
+				BodyContains: "error",
+				BodyRegex:    `#\d+`,
+				MaxLatency:   "1s",
+				Headers:      map[string]string{"content-type": "text/plain"},
+			},
+			want: Verdict{Passed: true},
+		},
+		{
+			name: "should fail with every reason",
+			assertions: Assertions{
+				Status:       []StatusRange{{200, 299}},
+				BodyContains: "ok",
+				BodyRegex:    `^\d+$`,
+				MaxLatency:   "500ms",
+				Headers:      map[string]string{"Content-Type": "json", "ETag": ""},
+			},
+			want: Verdict{
+				Passed: false,
+				Reasons: []string{
+					"status 500 is not expected",
+					`body does not contain "ok"`,
+					`body does not match "^\\d+$"`,
+					"latency 700ms exceeds 500ms",
+					`header Content-Type does not contain "json"`,
+					"header ETag is missing",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.assertions.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+				return
+			}
+			if got := tt.assertions.Check(output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
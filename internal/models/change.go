@@ -0,0 +1,54 @@
+package models
+
+import "yegorov-boris/affise-test-task/pkg/udiff"
+
+// MaxDiffChanges limits the changed lines of diffs, bigger diffs are omitted.
+const MaxDiffChanges = 1000
+
+// MaxDiffLines limits the lines of both bodies of diffs, diffs of bigger bodies are omitted.
+const MaxDiffLines = 100000
+
+// Change compares an output with the output of the same link in the previous job.
+type Change struct {
+	Changed bool `json:"changed"`
+	// New is set for the links missing in the previous job
+	New                bool   `json:"new,omitempty"`
+	StatusChanged      bool   `json:"status_changed,omitempty"`
+	BodyChanged        bool   `json:"body_changed,omitempty"`
+	PreviousStatusCode int    `json:"previous_status_code,omitempty"`
+	BodyHash           string `json:"body_hash"`
+	PreviousBodyHash   string `json:"previous_body_hash,omitempty"`
+	Diff               string `json:"diff,omitempty"`
+}
+
+// NewChange compares the output with the previous one, nil previous means the link is new.
+// The diff is made only when the previous body is stored.
+func NewChange(output Output, previous *Output, withDiff bool) Change {
+	change := Change{
+		BodyHash: output.ContentHash(),
+	}
+
+	if previous == nil {
+		change.Changed, change.New = true, true
+
+		return change
+	}
+
+	change.PreviousStatusCode = previous.StatusCode
+	// the previous body may be dropped, its hash is kept along with it
+	change.PreviousBodyHash = previous.ContentHash()
+	if len(previous.BodyHash) == 0 && previous.Change != nil {
+		change.PreviousBodyHash = previous.Change.BodyHash
+	}
+
+	change.StatusChanged = output.StatusCode != previous.StatusCode
+	change.BodyChanged = change.BodyHash != change.PreviousBodyHash
+	change.Changed = change.StatusChanged || change.BodyChanged
+
+	if withDiff && change.BodyChanged && ContentHash(previous.Body) == change.PreviousBodyHash {
+		// too big diffs are omitted
+		change.Diff, _ = udiff.Unified("previous", "current", previous.Body, output.Body, MaxDiffChanges, MaxDiffLines)
+	}
+
+	return change
+}
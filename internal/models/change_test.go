@@ -0,0 +1,78 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewChange(t *testing.T) {
+	type args struct {
+		output   Output
+		previous *Output
+		withDiff bool
+	}
+	page := Output{StatusCode: 200, Body: "a\nb\n"}
+	tests := []struct {
+		name string
+		args args
+		want Change
+	}{
+		{
+			name: "should mark a link without previous output as new",
+			args: args{output: page},
+			want: Change{Changed: true, New: true, BodyHash: ContentHash(page.Body)},
+		},
+		{
+			name: "should not mark the same output as changed",
+			args: args{output: page, previous: &page, withDiff: true},
+			want: Change{
+				PreviousStatusCode: 200,
+				BodyHash:           ContentHash(page.Body),
+				PreviousBodyHash:   ContentHash(page.Body),
+			},
+		},
+		{
+			name: "should detect a changed status code",
+			args: args{output: page, previous: &Output{StatusCode: 500, Body: page.Body}},
+			want: Change{
+				Changed:            true,
+				StatusChanged:      true,
+				PreviousStatusCode: 500,
+				BodyHash:           ContentHash(page.Body),
+				PreviousBodyHash:   ContentHash(page.Body),
+			},
+		},
+		{
+			name: "should detect a changed body with a diff",
+			args: args{output: page, previous: &Output{StatusCode: 200, Body: "a\nc\n"}, withDiff: true},
+			want: Change{
+				Changed:            true,
+				BodyChanged:        true,
+				PreviousStatusCode: 200,
+				BodyHash:           ContentHash(page.Body),
+				PreviousBodyHash:   ContentHash("a\nc\n"),
+				Diff:               "--- previous\n+++ current\n@@ -1,2 +1,2 @@\n a\n-c\n+b\n",
+			},
+		},
+		{
+			name: "should compare with the hash of a dropped previous body",
+			args: args{
+				output:   page,
+				previous: &Output{StatusCode: 200, Change: &Change{BodyHash: ContentHash(page.Body)}},
+				withDiff: true,
+			},
+			want: Change{
+				PreviousStatusCode: 200,
+				BodyHash:           ContentHash(page.Body),
+				PreviousBodyHash:   ContentHash(page.Body),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewChange(tt.args.output, tt.args.previous, tt.args.withDiff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewChange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// Extraction lists the extractors applied to the outputs of a job.
+type Extraction struct {
+	Title     bool
+	Meta      bool
+	Links     bool
+	Text      bool
+	JSONPaths []string
+	DropBody  bool
+}
+
+// Extracted is the data extracted from an output body.
+type Extracted struct {
+	Title string            `json:"title,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"`
+	Links []string          `json:"links,omitempty"`
+	Text  string            `json:"text,omitempty"`
+	JSON  map[string]any    `json:"json,omitempty"`
+}
+
+func (e *Extraction) IsEmpty() bool {
+	return !e.Title && !e.Meta && !e.Links && !e.Text && len(e.JSONPaths) == 0 && !e.DropBody
+}
+
+// HTML reports whether any of HTML extractors is requested.
+func (e *Extraction) HTML() bool {
+	return e.Title || e.Meta || e.Links || e.Text
+}
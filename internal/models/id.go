@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// RandomIDLength is the length of a hex encoded random 128-bit ID.
+const RandomIDLength = 32
+
+var ErrInvalidID = errors.New("invalid ID")
+
+// ParseID accepts both sequential (decimal) and random (hex encoded 128-bit) IDs
+// and returns the ID in its canonical form.
+func ParseID(s string) (string, error) {
+	if len(s) == RandomIDLength {
+		if _, err := hex.DecodeString(s); err == nil {
+			return strings.ToLower(s), nil
+		}
+	}
+
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return "", ErrInvalidID
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
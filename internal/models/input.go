@@ -32,3 +32,39 @@ func (i *Input) Validate(maxLinksPerIn uint32) error {
 
 	return nil
 }
+
+// Unique returns the links without duplicates and the index of the unique link for every link.
+// Links are compared by their keys, e.g. normalized URLs, the unique links are replaced by their keys.
+func (i *Input) Unique(key func(string) (string, error)) (Input, []int, error) {
+	unique := make(Input, 0, len(*i))
+	index := make([]int, len(*i))
+	seen := make(map[string]int, len(*i))
+	for n, link := range *i {
+		k, err := key(link)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get key of a link: %w", err)
+		}
+
+		j, ok := seen[k]
+		if !ok {
+			j = len(unique)
+			seen[k] = j
+			unique = append(unique, k)
+		}
+		index[n] = j
+	}
+
+	return unique, index, nil
+}
+
+// Expand maps the outputs of the unique links back to the links they were made from,
+// so the outputs line up with the links.
+func (i *Input) Expand(outputs []Output, index []int) []Output {
+	expanded := make([]Output, len(*i))
+	for n, link := range *i {
+		expanded[n] = outputs[index[n]]
+		expanded[n].URL = link
+	}
+
+	return expanded
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"yegorov-boris/affise-test-task/pkg/urlnorm"
+)
+
+func TestInput_Unique(t *testing.T) {
+	exact := func(link string) (string, error) {
+		return link, nil
+	}
+	normalize := func(link string) (string, error) {
+		return urlnorm.Normalize(link, true)
+	}
+	tests := []struct {
+		name       string
+		input      Input
+		key        func(string) (string, error)
+		wantUnique Input
+		wantIndex  []int
+		wantErr    bool
+	}{
+		{
+			name:       "should keep distinct links in order",
+			input:      Input{"https://example.com/b", "https://example.com/a"},
+			key:        exact,
+			wantUnique: Input{"https://example.com/b", "https://example.com/a"},
+			wantIndex:  []int{0, 1},
+		},
+		{
+			name: "should map every duplicate to the first occurrence",
+			input: Input{
+				"https://example.com/a",
+				"https://example.com/b",
+				"https://example.com/a",
+				"https://example.com/a",
+				"https://example.com/b",
+			},
+			key:        exact,
+			wantUnique: Input{"https://example.com/a", "https://example.com/b"},
+			wantIndex:  []int{0, 1, 0, 0, 1},
+		},
+		{
+			name:       "should not compare links which differ only in case without normalization",
+			input:      Input{"https://example.com/a", "HTTPS://EXAMPLE.COM/a"},
+			key:        exact,
+			wantUnique: Input{"https://example.com/a", "HTTPS://EXAMPLE.COM/a"},
+			wantIndex:  []int{0, 1},
+		},
+		{
+			name: "should map normalized duplicates to the normalized link",
+			input: Input{
+				"HTTPS://Example.com:443/a/./b?y=2&x=1",
+				"https://example.com/c",
+				"https://example.com/a/b?x=1&y=2",
+			},
+			key:        normalize,
+			wantUnique: Input{"https://example.com/a/b?x=1&y=2", "https://example.com/c"},
+			wantIndex:  []int{0, 1, 0},
+		},
+		{
+			name:    "should fail for an invalid link",
+			input:   Input{"https://example.com/a", "not a link"},
+			key:     normalize,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unique, index, err := tt.input.Unique(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unique() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(unique, tt.wantUnique) {
+				t.Errorf("Unique() unique = %v, want %v", unique, tt.wantUnique)
+			}
+			if !reflect.DeepEqual(index, tt.wantIndex) {
+				t.Errorf("Unique() index = %v, want %v", index, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestInput_Expand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   Input
+		outputs []Output
+		index   []int
+		want    []Output
+	}{
+		{
+			name:  "should restore the original links of duplicates",
+			input: Input{"HTTPS://Example.com/a", "https://example.com/b", "https://example.com/a"},
+			outputs: []Output{
+				{URL: "https://example.com/a", StatusCode: 200, Body: "a"},
+				{URL: "https://example.com/b", StatusCode: 404, Body: "b"},
+			},
+			index: []int{0, 1, 0},
+			want: []Output{
+				{URL: "HTTPS://Example.com/a", StatusCode: 200, Body: "a"},
+				{URL: "https://example.com/b", StatusCode: 404, Body: "b"},
+				{URL: "https://example.com/a", StatusCode: 200, Body: "a"},
+			},
+		},
+		{
+			name:  "should line up the outputs with the links in order",
+			input: Input{"https://example.com/b", "https://example.com/b", "https://example.com/a"},
+			outputs: []Output{
+				{URL: "https://example.com/b", StatusCode: 200, Body: "b"},
+				{URL: "https://example.com/a", StatusCode: 500, Body: "failed"},
+			},
+			index: []int{0, 0, 1},
+			want: []Output{
+				{URL: "https://example.com/b", StatusCode: 200, Body: "b"},
+				{URL: "https://example.com/b", StatusCode: 200, Body: "b"},
+				{URL: "https://example.com/a", StatusCode: 500, Body: "failed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.Expand(tt.outputs, tt.index); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
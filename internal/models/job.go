@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInterrupted is the cause of the jobs canceled on shutdown.
+	ErrInterrupted = errors.New("interrupted by shutdown")
+	// ErrShuttingDown is returned when a job is started after shutdown has begun.
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+type Job struct {
+	ID        string    `json:"id"`
+	TokenHash string    `json:"token_hash"`
+	CreatedAt time.Time `json:"created_at"`
+	KeyName   string    `json:"key_name,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	// IdempotencyKeyHash is set for jobs created with an Idempotency-Key header.
+	IdempotencyKeyHash string `json:"idempotency_key_hash,omitempty"`
+	// ReplayTokenHashes are the hashes of the other access tokens of the job:
+	// issued to the repeated requests or of the schedule which created the job.
+	ReplayTokenHashes []string `json:"replay_token_hashes,omitempty"`
+	ScheduleID        string   `json:"schedule_id,omitempty"`
+	// PreviousJobID is the job the outputs are compared with.
+	PreviousJobID string `json:"previous_job_id,omitempty"`
+	// ResultHash is the hex encoded SHA-256 of the stored result, set when the job is finished.
+	ResultHash string `json:"result_hash,omitempty"`
+	// AssertionsPassed is set when the job with assertions is finished.
+	AssertionsPassed *bool `json:"assertions_passed,omitempty"`
+}
+
+// IdempotencyKey binds an Idempotency-Key header value to the job created with it.
+type IdempotencyKey struct {
+	Hash      string    `json:"hash"`
+	JobID     string    `json:"job_id"`
+	BodyHash  string    `json:"body_hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
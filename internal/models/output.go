@@ -1,7 +1,39 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
 type Output struct {
 	URL        string `json:"url"`
 	StatusCode int    `json:"status_code"`
 	Body       string `json:"body"`
+	// BodyHash is kept when the body is dropped
+	BodyHash  string        `json:"body_hash,omitempty"`
+	Header    http.Header   `json:"-"`
+	Cached    bool          `json:"cached,omitempty"`
+	Age       int64         `json:"age,omitempty"` // seconds since the cached response was fetched or revalidated
+	Extracted *Extracted    `json:"extracted,omitempty"`
+	Verdict   *Verdict      `json:"verdict,omitempty"`
+	Change    *Change       `json:"change,omitempty"`
+	Latency   time.Duration `json:"-"`
+}
+
+// ContentHash returns the hex encoded SHA-256 of the content.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash returns the stored hash of the body or computes it.
+func (o *Output) ContentHash() string {
+	if len(o.BodyHash) != 0 {
+		return o.BodyHash
+	}
+
+	return ContentHash(o.Body)
 }
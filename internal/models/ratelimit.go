@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type RateLimit struct {
+	Allowed   bool
+	Limit     uint32
+	Remaining uint32
+	// Reset is the time left until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time left until the next request is allowed
+	RetryAfter time.Duration
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"yegorov-boris/affise-test-task/pkg/cron"
+)
+
+// MinScheduleInterval keeps schedules from flooding the service with jobs.
+const MinScheduleInterval = time.Second
+
+// Schedule creates a job with the links every interval or at the times of the cron expression.
+type Schedule struct {
+	ID string `json:"id"`
+	Request
+	Interval string `json:"interval,omitempty"`
+	Cron     string `json:"cron,omitempty"`
+	// Options are the query parameters of the created jobs, e.g. "normalize=true&extract=title"
+	Options string `json:"options,omitempty"`
+	// Compare makes every job compare its outputs with the previous job of the schedule
+	Compare   bool       `json:"compare,omitempty"`
+	KeyName   string     `json:"key_name,omitempty"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastJobID string     `json:"last_job_id,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+}
+
+var ErrInvalidSchedule = errors.New("either interval or cron should be set")
+
+// Validate checks the timing and the assertions of the schedule.
+func (s *Schedule) Validate() error {
+	if (len(s.Interval) == 0) == (len(s.Cron) == 0) {
+		return ErrInvalidSchedule
+	}
+
+	if len(s.Interval) != 0 {
+		interval, err := time.ParseDuration(s.Interval)
+		if err != nil || interval < MinScheduleInterval {
+			return fmt.Errorf("interval should be a duration of at least %s, got %q", MinScheduleInterval, s.Interval)
+		}
+	}
+
+	if len(s.Cron) != 0 {
+		expr, err := cron.Parse(s.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+
+		// expressions like "0 0 31 2 *" are valid but never run
+		if _, err := expr.Next(time.Now().UTC()); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", s.Cron, err)
+		}
+	}
+
+	return s.Request.Validate()
+}
+
+// Next returns the time of the first run after t. Interval runs are aligned to the creation time,
+// cron expressions are matched in UTC.
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	if len(s.Cron) != 0 {
+		expr, err := cron.Parse(s.Cron)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+		}
+
+		return expr.Next(t.UTC())
+	}
+
+	interval, err := time.ParseDuration(s.Interval)
+	if err != nil || interval <= 0 {
+		return time.Time{}, fmt.Errorf("invalid interval %q", s.Interval)
+	}
+
+	if t.Before(s.CreatedAt) {
+		return s.CreatedAt.Add(interval), nil
+	}
+
+	return s.CreatedAt.Add((t.Sub(s.CreatedAt)/interval + 1) * interval), nil
+}
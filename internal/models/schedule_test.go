@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	createdAt := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	links := Request{Links: []Link{{URL: "https://example.com"}}}
+	tests := []struct {
+		name     string
+		schedule Schedule
+		from     time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "should align intervals to the creation time",
+			schedule: Schedule{Request: links, Interval: "5m", CreatedAt: createdAt},
+			from:     createdAt.Add(12 * time.Minute),
+			want:     createdAt.Add(15 * time.Minute),
+		},
+		{
+			name:     "should run the first interval after the creation",
+			schedule: Schedule{Request: links, Interval: "5m", CreatedAt: createdAt},
+			from:     createdAt,
+			want:     createdAt.Add(5 * time.Minute),
+		},
+		{
+			name:     "should follow the cron expression",
+			schedule: Schedule{Request: links, Cron: "0 * * * *", CreatedAt: createdAt},
+			from:     createdAt,
+			want:     time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "should fail for both interval and cron",
+			schedule: Schedule{Request: links, Interval: "5m", Cron: "0 * * * *"},
+			wantErr:  true,
+		},
+		{
+			name:     "should fail for too short interval",
+			schedule: Schedule{Request: links, Interval: "10ms"},
+			wantErr:  true,
+		},
+		{
+			name:     "should fail for an invalid cron expression",
+			schedule: Schedule{Request: links, Cron: "0 * * *"},
+			wantErr:  true,
+		},
+		{
+			name:     "should fail for a cron expression which never matches",
+			schedule: Schedule{Request: links, Cron: "0 0 31 2 *"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if err == nil {
+				var got time.Time
+				if got, err = tt.schedule.Next(tt.from); err == nil && !got.Equal(tt.want) {
+					t.Errorf("Next() got = %v, want %v", got, tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() or Next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
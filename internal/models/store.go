@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type StoreEntry struct {
+	ID      string
+	ModTime time.Time
+	Size    int64
+	// HasResult is false for the jobs still in progress
+	HasResult bool
+}
@@ -0,0 +1,44 @@
+package multiplexer
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"yegorov-boris/affise-test-task/configs"
+	"yegorov-boris/affise-test-task/pkg/logctx"
+)
+
+// newLogger returns the logger along with a function closing its output.
+func newLogger(cfg *configs.Config) (*slog.Logger, func() error, error) {
+	var (
+		w     io.Writer
+		close = func() error { return nil }
+	)
+
+	switch cfg.LogOutput {
+	case configs.LogOutputStdout:
+		w = os.Stdout
+	case configs.LogOutputStderr:
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(cfg.LogOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file %q: %w", cfg.LogOutput, err)
+		}
+
+		w = f
+		close = f.Close
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	}
+
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if cfg.LogFormat == configs.LogFormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(logctx.NewHandler(handler)), close, nil
+}
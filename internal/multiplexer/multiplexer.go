@@ -2,59 +2,209 @@ package multiplexer
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
-	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 	"yegorov-boris/affise-test-task/configs"
+	"yegorov-boris/affise-test-task/internal/contracts"
 	"yegorov-boris/affise-test-task/internal/handlers"
 	"yegorov-boris/affise-test-task/internal/middleware"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/internal/services/apikeys"
+	"yegorov-boris/affise-test-task/internal/services/cache"
 	"yegorov-boris/affise-test-task/internal/services/cleaner"
+	"yegorov-boris/affise-test-task/internal/services/coalescer"
+	"yegorov-boris/affise-test-task/internal/services/extractor"
+	"yegorov-boris/affise-test-task/internal/services/health"
+	"yegorov-boris/affise-test-task/internal/services/metrics"
 	"yegorov-boris/affise-test-task/internal/services/progress"
+	"yegorov-boris/affise-test-task/internal/services/ratelimit"
+	"yegorov-boris/affise-test-task/internal/services/scheduler"
 	"yegorov-boris/affise-test-task/internal/services/scraper"
 	"yegorov-boris/affise-test-task/internal/services/store"
 	"yegorov-boris/affise-test-task/pkg/httpclient"
+	"yegorov-boris/affise-test-task/pkg/semaphore"
+	"yegorov-boris/affise-test-task/pkg/tracing"
 )
 
-func Run(cfg *configs.Config) (func() error, error) {
+// interruptGracePeriod limits waiting for the jobs interrupted on shutdown to save their results.
+const interruptGracePeriod = 5 * time.Second
+
+// Run starts the multiplexer. It returns functions to reload the config with load and to shutdown gracefully.
+func Run(
+	cfg *configs.Config,
+	load func() (*configs.Config, error),
+) (func() ([]models.ConfigChange, error), func() error, error) {
 	// Logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	logger.Info(fmt.Sprintf("starting with config: %+v", cfg))
+	logger, closeLogger, err := newLogger(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	logger.Info("starting", slog.Any("config", cfg))
+
+	// Tracing
+	var exporter tracing.Exporter = tracing.NopExporter{}
+	switch cfg.TracingExporter {
+	case configs.TracingExporterOTLP:
+		exporter = tracing.NewHTTPExporter(cfg.TracingEndpoint, cfg.HTTPClientTimeout)
+	case configs.TracingExporterFile:
+		exporter = tracing.NewFileExporter(cfg.TracingFile)
+	}
+	tracer := tracing.New(cfg.TracingServiceName, exporter, func(err error) {
+		logger.Error("failed to export spans", slog.Any("error", err))
+	})
 
 	// State
-	state, err := progress.New(cfg.StorePath)
+	state, err := progress.New(cfg.StorePath, cfg.IDFormat == configs.IDFormatRandom)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create state: %w", err)
+	}
+
+	// Set when graceful shutdown starts
+	var shuttingDown atomic.Bool
+
+	// Concurrency limiter
+	slots := semaphore.New(int(cfg.MaxParallelIn))
+
+	// Store
+	resultsStore := store.New(logger, cfg.StorePath)
+
+	// Authentication
+	protected := func(endpoint string, inner contracts.Handler) contracts.Handler {
+		return inner
+	}
+	var keys contracts.APIKeys
+	if len(cfg.APIKeysFile) != 0 {
+		loaded, err := apikeys.Load(cfg.APIKeysFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load API keys: %w", err)
+		}
+		keys = loaded
+
+		protected = func(endpoint string, inner contracts.Handler) contracts.Handler {
+			return middleware.NewAuth(keys, endpoint, inner)
+		}
+	}
+
+	// Requests rate limiter
+	proxies, err := middleware.NewTrustedProxies(cfg.TrustedProxies)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create state: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse trusted proxies: %w", err)
 	}
 
-	// Rate limiter
-	bucket := make(chan struct{}, cfg.MaxParallelIn)
+	limitSchedules := func(inner contracts.Handler) contracts.Handler {
+		return inner
+	}
+	if cfg.RateLimitRPS > 0 {
+		limiter := ratelimit.New(cfg.RateLimitRPS, cfg.RateLimitBurst, int(cfg.RateLimitMaxClients))
+		clientKey := middleware.ClientKeyByIP(proxies)
+		if cfg.RateLimitBy == configs.RateLimitByAPIKey {
+			clientKey = middleware.ClientKeyByAPIKey(proxies)
+		}
+
+		authenticated := protected
+		protected = func(endpoint string, inner contracts.Handler) contracts.Handler {
+			return authenticated(endpoint, middleware.NewTokenBucket(limiter, clientKey, inner))
+		}
+		limitSchedules = func(inner contracts.Handler) contracts.Handler {
+			return middleware.NewTokenBucket(limiter, middleware.ClientKeyBySchedule(), inner)
+		}
+	}
+
+	// Metrics
+	registry := metrics.NewRegistry()
+	registry.NewGaugeFunc("multiplexer_jobs_in_flight", "Jobs in progress.", func() float64 {
+		return float64(state.Len())
+	})
+	registry.NewGaugeFunc("multiplexer_rate_limiter_slots_in_use", "Concurrently processed POST requests.", func() float64 {
+		return float64(slots.Len())
+	})
+	registry.NewGaugeFunc("multiplexer_rate_limiter_slots", "Maximum concurrently processed POST requests.", func() float64 {
+		return float64(slots.Cap())
+	})
+	registry.NewGaugeFunc("multiplexer_store_bytes", "Size of stored results and job records.", func() float64 {
+		entries, err := resultsStore.List()
+		if err != nil {
+			logger.Error("failed to list stored results", slog.Any("error", err))
+			return math.NaN()
+		}
+
+		var size int64
+		for _, e := range entries {
+			size += e.Size
+		}
+
+		return float64(size)
+	})
 
 	// HTTP Client
-	httpClient := httpclient.New(cfg.HTTPClientTimeout)
+	var decorators []httpclient.Decorator
+	if cfg.ForwardRequestID {
+		decorators = append(decorators, func(r *http.Request) {
+			if requestID, ok := r.Context().Value(contracts.ContextKey("requestID")).(string); ok {
+				r.Header.Set(middleware.RequestIDHeader, requestID)
+			}
+		})
+	}
+	client := httpclient.New(cfg.HTTPClientTimeout, decorators...)
+	var httpClient contracts.HTTPClient = metrics.InstrumentHTTPClient(registry, client)
+
+	// Requests coalescing
+	if cfg.CoalesceRequests {
+		shared := coalescer.New(httpClient)
+		registry.NewGaugeFunc("multiplexer_coalesced_requests_in_flight", "Shared outgoing requests in progress.", func() float64 {
+			return float64(shared.InFlight())
+		})
+		httpClient = shared
+	}
+
+	// Responses cache
+	if cfg.CacheMaxBytes > 0 {
+		responses := cache.New(httpClient, int64(cfg.CacheMaxBytes))
+		registry.NewGaugeFunc("multiplexer_cache_bytes", "Size of cached responses.", func() float64 {
+			return float64(responses.Size())
+		})
+		httpClient = responses
+	}
+
+	// Limits which can be changed by reload
+	var maxLinksPerIn atomic.Uint32
+	maxLinksPerIn.Store(cfg.MaxLinksPerIn)
+	linksScraper := scraper.New(logger, cfg.MaxParallelOutPerIn, httpClient)
 
 	// HTTP Server
 	mux := http.NewServeMux()
 	linksPath, err := url.JoinPath(cfg.HTTPBasePath, "/links")
 	if err != nil {
-		return nil, fmt.Errorf("failed to join path: %w", err)
+		return nil, nil, fmt.Errorf("failed to join path: %w", err)
 	}
 
-	handlePost := middleware.NewRateLimiter(
-		bucket,
-		middleware.NewLogger(
-			logger,
-			handlers.NewPost(
-				cfg.MaxLinksPerIn,
-				state,
-				scraper.New(logger, cfg.MaxParallelOutPerIn, httpClient),
-				store.New(logger, cfg.StorePath),
+	// jobs of schedules are created by the same chain as the ones of clients,
+	// the rate limiter of schedules is keyed by API key or schedule ID
+	createJob := middleware.NewKeyJobsLimiter(
+		middleware.NewRateLimiter(
+			slots,
+			middleware.NewLogger(
+				logger,
+				handlers.NewPost(
+					logger,
+					maxLinksPerIn.Load,
+					cfg.IdempotencyWindow,
+					state,
+					metrics.InstrumentScraper(registry, linksScraper),
+					extractor.New(),
+					resultsStore,
+				),
 			),
 		),
 	)
+	handlePost := middleware.NewDrain(&shuttingDown, protected(apikeys.EndpointLinksCreate, createJob))
 
 	mux.HandleFunc(linksPath, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -66,14 +216,14 @@ func Run(cfg *configs.Config) (func() error, error) {
 		handlePost(w, r)
 	})
 
-	handleGet := middleware.NewLogger(
+	handleGet := protected(apikeys.EndpointLinksGet, middleware.NewLogger(
 		logger,
-		handlers.NewGet(linksPath, cfg.StorePath, state),
-	)
-	handleDelete := middleware.NewLogger(
+		handlers.NewGet(linksPath, state, resultsStore),
+	))
+	handleDelete := protected(apikeys.EndpointLinksDelete, middleware.NewLogger(
 		logger,
-		handlers.NewDelete(linksPath, state),
-	)
+		handlers.NewDelete(linksPath, state, resultsStore),
+	))
 	mux.HandleFunc(fmt.Sprintf("%s/", linksPath), func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -87,9 +237,135 @@ func Run(cfg *configs.Config) (func() error, error) {
 		}
 	})
 
+	// Schedules
+	jobsScheduler, err := scheduler.New(
+		logger,
+		linksPath,
+		middleware.NewDrain(&shuttingDown, limitSchedules(createJob)),
+		state,
+		resultsStore,
+		keys,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create scheduler: %w", err)
+	}
+	logger.Info("scheduler started")
+
+	schedulesPath, err := url.JoinPath(cfg.HTTPBasePath, "/schedules")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to join path: %w", err)
+	}
+	handleScheduleCreate := middleware.NewDrain(&shuttingDown, protected(apikeys.EndpointSchedulesCreate, middleware.NewLogger(
+		logger,
+		handlers.NewScheduleCreate(maxLinksPerIn.Load, jobsScheduler),
+	)))
+	handleScheduleList := protected(apikeys.EndpointSchedulesList, middleware.NewLogger(
+		logger,
+		handlers.NewScheduleList(jobsScheduler),
+	))
+	mux.HandleFunc(schedulesPath, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleScheduleCreate(w, r)
+		case http.MethodGet:
+			handleScheduleList(w, r)
+		default:
+			errMsg := fmt.Sprintf("Sorry, only %s and %s methods are supported for this path.", http.MethodPost, http.MethodGet)
+			http.Error(w, errMsg, http.StatusMethodNotAllowed)
+			return
+		}
+	})
+
+	handleScheduleDelete := protected(apikeys.EndpointSchedulesDelete, middleware.NewLogger(
+		logger,
+		handlers.NewScheduleDelete(schedulesPath, jobsScheduler),
+	))
+	mux.HandleFunc(fmt.Sprintf("%s/", schedulesPath), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodDelete)
+			http.Error(w, errMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleScheduleDelete(w, r)
+	})
+
+	// the purge endpoint is available only with API keys, as it removes the results of all clients
+	if len(cfg.APIKeysFile) != 0 {
+		purgePath, err := url.JoinPath(cfg.HTTPBasePath, "/admin/purge")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to join path: %w", err)
+		}
+		handlePurge := protected(apikeys.EndpointAdminPurge, middleware.NewLogger(
+			logger,
+			handlers.NewPurge(resultsStore),
+		))
+		mux.HandleFunc(purgePath, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodPost)
+				http.Error(w, errMsg, http.StatusMethodNotAllowed)
+				return
+			}
+
+			handlePurge(w, r)
+		})
+	}
+
+	// Config reload applies the limits which can be safely changed at runtime
+	var reloading sync.Mutex
+	current := cfg
+	reload := func() ([]models.ConfigChange, error) {
+		reloading.Lock()
+		defer reloading.Unlock()
+
+		next, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := current.Diff(next)
+		if err != nil {
+			return nil, err
+		}
+
+		maxLinksPerIn.Store(next.MaxLinksPerIn)
+		slots.Resize(int(next.MaxParallelIn))
+		linksScraper.SetMaxParallelOutPerIn(next.MaxParallelOutPerIn)
+		client.SetTimeout(next.HTTPClientTimeout)
+		current = next
+
+		for _, c := range changes {
+			logger.Info("config changed", slog.String("field", c.Field), slog.Any("old", c.Old), slog.Any("new", c.New))
+		}
+		logger.Info("config reloaded", slog.Int("changes", len(changes)))
+
+		return changes, nil
+	}
+
+	// the reload endpoint is available only with API keys, as it must be authenticated
+	if len(cfg.APIKeysFile) != 0 {
+		reloadPath, err := url.JoinPath(cfg.HTTPBasePath, "/admin/reload")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to join path: %w", err)
+		}
+		handleReload := protected(apikeys.EndpointAdminReload, middleware.NewLogger(
+			logger,
+			handlers.NewReload(reload),
+		))
+		mux.HandleFunc(reloadPath, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodPost)
+				http.Error(w, errMsg, http.StatusMethodNotAllowed)
+				return
+			}
+
+			handleReload(w, r)
+		})
+	}
+
 	docsPath, err := url.JoinPath(cfg.HTTPBasePath, "/docs")
 	if err != nil {
-		return nil, fmt.Errorf("failed to join path: %w", err)
+		return nil, nil, fmt.Errorf("failed to join path: %w", err)
 	}
 	handleDocs := handlers.NewDocs(docsPath)
 	mux.HandleFunc(fmt.Sprintf("%s/", docsPath), func(w http.ResponseWriter, r *http.Request) {
@@ -102,37 +378,131 @@ func Run(cfg *configs.Config) (func() error, error) {
 		handleDocs(w, r)
 	})
 
-	srv := http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.HTTPPort),
-		Handler: mux,
-	}
-	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatal(err)
+	handleMetrics := middleware.NewLogger(logger, handlers.NewMetrics(registry))
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodGet)
+			http.Error(w, errMsg, http.StatusMethodNotAllowed)
+			return
 		}
-	}()
-	logger.Info(fmt.Sprintf("http server listening on port %d", cfg.HTTPPort))
+
+		handleMetrics(w, r)
+	})
 
 	// GC old files
-	filesCleaner := cleaner.New(cfg.StoreTimeout, cfg.StorePath, logger)
+	filesCleaner := cleaner.New(cfg.StoreTimeout, state, resultsStore, logger)
 	logger.Info("files cleaner started")
 
-	return func() error {
+	// Health checks
+	checker := health.New()
+	checker.Add("store", resultsStore.Ping)
+	checker.Add("cleaner", func() error {
+		if !filesCleaner.Alive() {
+			return errors.New("files cleaner has stopped")
+		}
+
+		return nil
+	})
+	checker.Add("rate_limiter", func() error {
+		inUse, size := slots.Len(), slots.Cap()
+		if saturation := float64(inUse) / float64(size); saturation > cfg.ReadinessMaxSaturation {
+			return fmt.Errorf("%d of %d slots are in use", inUse, size)
+		}
+
+		return nil
+	})
+	checker.Add("shutdown", func() error {
+		if shuttingDown.Load() {
+			return errors.New("graceful shutdown started")
+		}
+
+		return nil
+	})
+
+	handleLiveness := middleware.NewLogger(logger, handlers.NewLiveness())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodGet)
+			http.Error(w, errMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleLiveness(w, r)
+	})
+
+	handleReadiness := middleware.NewLogger(logger, handlers.NewReadiness(checker))
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			errMsg := fmt.Sprintf("Sorry, only %s method is supported for this path.", http.MethodGet)
+			http.Error(w, errMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleReadiness(w, r)
+	})
+
+	srv, err := newServer(cfg, logger, middleware.NewRequestID(middleware.NewAccessLog(logger, proxies, middleware.NewTracing(tracer, mux))))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create HTTP server: %w", err)
+	}
+
+	// ServeTLS writes srv.TLSConfig once listening starts
+	useTLS := srv.TLSConfig != nil
+	if err := listen(srv, cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to start HTTP server: %w", err)
+	}
+	logger.Info(
+		"http server listening",
+		slog.Uint64("port", uint64(cfg.HTTPPort)),
+		slog.Bool("tls", useTLS),
+		slog.String("unix_socket", cfg.UnixSocket),
+	)
+
+	return reload, func() error {
 		logger.Info("graceful shutdown started")
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		// let load balancers notice the instance is not ready before it stops accepting connections
+		shuttingDown.Store(true)
+		srv.SetKeepAlivesEnabled(false)
+		time.Sleep(cfg.ShutdownDrainDelay)
 
-		if err := srv.Shutdown(context.Background()); err != nil {
-			return fmt.Errorf("failed to shutdown HTTP server: %w", err)
+		jobsScheduler.Shutdown()
+		logger.Info("scheduler stopped")
+
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warn("shutdown timeout exceeded, closing connections", slog.Any("error", err))
+			if err := srv.Close(); err != nil {
+				return fmt.Errorf("failed to close HTTP server: %w", err)
+			}
 		}
 		logger.Info("http server stopped")
 
 		filesCleaner.Shutdown()
 
-		for !state.IsEmpty() {
-			time.Sleep(cfg.GracefulShutdownStep)
+		// handlers still running after Close can not start jobs anymore
+		state.Close()
+		if err := state.Wait(ctx); err != nil {
+			logger.Warn("shutdown timeout exceeded, interrupting jobs", slog.Int("jobs", state.CancelAll(models.ErrInterrupted)))
+
+			// interrupted jobs finish promptly, their results are saved
+			graceCtx, cancelGrace := context.WithTimeout(context.Background(), interruptGracePeriod)
+			defer cancelGrace()
+			if err := state.Wait(graceCtx); err != nil {
+				logger.Warn("interrupted jobs did not finish in time", slog.Int("jobs", state.Len()))
+			}
+		}
+		if state.Len() == 0 {
+			logger.Info("all jobs finished")
+		}
+
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			return fmt.Errorf("failed to shutdown tracer: %w", err)
 		}
 
 		logger.Info("graceful shutdown finished")
 
-		return nil
+		return closeLogger()
 	}, nil
 }
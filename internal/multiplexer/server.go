@@ -0,0 +1,107 @@
+package multiplexer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"yegorov-boris/affise-test-task/configs"
+	"yegorov-boris/affise-test-task/pkg/tlscert"
+)
+
+// newServer returns the HTTP server, with TLS when a certificate is set.
+func newServer(cfg *configs.Config, logger *slog.Logger, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.HTTPPort),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    int(cfg.HTTPMaxHeaderBytes),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	// a non-nil empty map disables HTTP/2
+	if !cfg.HTTP2 {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	if len(cfg.TLSCertFile) != 0 {
+		certs, err := tlscert.New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSReloadInterval, func(err error) {
+			if err != nil {
+				logger.Error("failed to reload TLS certificate", slog.Any("error", err))
+				return
+			}
+
+			logger.Info("TLS certificate reloaded")
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	return srv, nil
+}
+
+// listen starts serving on the TCP port and the optional Unix socket.
+func listen(srv *http.Server, cfg *configs.Config) error {
+	tcpListener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q: %w", srv.Addr, err)
+	}
+
+	var unixListener net.Listener
+	if len(cfg.UnixSocket) != 0 {
+		// a socket left by a killed process makes listening fail
+		if info, err := os.Lstat(cfg.UnixSocket); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			if err := os.Remove(cfg.UnixSocket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				_ = tcpListener.Close()
+
+				return fmt.Errorf("failed to remove stale socket %q: %w", cfg.UnixSocket, err)
+			}
+		}
+
+		unixListener, err = net.Listen("unix", cfg.UnixSocket)
+		if err != nil {
+			_ = tcpListener.Close()
+
+			return fmt.Errorf("failed to listen on %q: %w", cfg.UnixSocket, err)
+		}
+	}
+
+	// ServeTLS writes srv.TLSConfig, so it is not read after serving starts
+	useTLS := srv.TLSConfig != nil
+	go func() {
+		serve := srv.Serve
+		if useTLS {
+			serve = func(l net.Listener) error {
+				return srv.ServeTLS(l, "", "")
+			}
+		}
+
+		if err := serve(tcpListener); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	if unixListener != nil {
+		go func() {
+			if err := srv.Serve(unixListener); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/pkg/token"
+)
+
+const (
+	EndpointLinksCreate = "links.create"
+	EndpointLinksGet    = "links.get"
+	EndpointLinksDelete = "links.delete"
+	EndpointAdminPurge  = "admin.purge"
+	EndpointAdminReload = "admin.reload"
+
+	EndpointSchedulesCreate = "schedules.create"
+	EndpointSchedulesList   = "schedules.list"
+	EndpointSchedulesDelete = "schedules.delete"
+)
+
+var endpoints = map[string]struct{}{
+	EndpointLinksCreate: {},
+	EndpointLinksGet:    {},
+	EndpointLinksDelete: {},
+	EndpointAdminPurge:  {},
+	EndpointAdminReload: {},
+
+	EndpointSchedulesCreate: {},
+	EndpointSchedulesList:   {},
+	EndpointSchedulesDelete: {},
+}
+
+type (
+	Keys struct {
+		// keys are indexed by hash, so lookups do not depend on the key bytes
+		keys  map[string]*Key
+		names map[string]*Key
+	}
+
+	Key struct {
+		Name              string   `json:"name"`
+		Key               string   `json:"key"`
+		Endpoints         []string `json:"endpoints"`
+		MaxLinksPerIn     uint32   `json:"max_links_per_in"`
+		MaxConcurrentJobs uint32   `json:"max_concurrent_jobs"`
+		DailyLinkQuota    uint32   `json:"daily_link_quota"`
+
+		m         sync.Mutex
+		jobs      uint32
+		day       string
+		linksUsed uint32
+	}
+)
+
+/*
+Load reads API keys from a JSON file like
+
+	[
+	  {
+	    "name": "team-a",
+	    "key": "secret",
+	    "endpoints": ["links.create", "links.get", "links.delete"],
+	    "max_links_per_in": 10,
+	    "max_concurrent_jobs": 5,
+	    "daily_link_quota": 1000
+	  }
+	]
+
+Empty endpoints allow all the endpoints, zero limits mean no limit.
+*/
+func Load(name string) (*Keys, error) {
+	var list []*Key
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode API keys file from JSON: %w", err)
+	}
+
+	k := &Keys{
+		keys:  make(map[string]*Key, len(list)),
+		names: make(map[string]*Key, len(list)),
+	}
+	for i, key := range list {
+		if len(key.Name) == 0 {
+			return nil, fmt.Errorf("API key #%d has no name", i)
+		}
+
+		if _, ok := k.names[key.Name]; ok {
+			return nil, fmt.Errorf("API key name %q is not unique", key.Name)
+		}
+		k.names[key.Name] = key
+
+		if len(key.Key) == 0 {
+			return nil, fmt.Errorf("API key %q is empty", key.Name)
+		}
+
+		hash := token.Hash(key.Key)
+		if _, ok := k.keys[hash]; ok {
+			return nil, fmt.Errorf("API key %q is not unique", key.Name)
+		}
+
+		for _, e := range key.Endpoints {
+			if _, ok := endpoints[e]; !ok {
+				return nil, fmt.Errorf("API key %q has unknown endpoint %q", key.Name, e)
+			}
+		}
+
+		k.keys[hash] = key
+	}
+
+	return k, nil
+}
+
+func (k *Keys) Lookup(key string) (contracts.APIKey, bool) {
+	found, ok := k.keys[token.Hash(key)]
+
+	return found, ok
+}
+
+// Named finds the key by name, e.g. to create jobs of a schedule on behalf of its owner.
+func (k *Keys) Named(name string) (contracts.APIKey, bool) {
+	found, ok := k.names[name]
+
+	return found, ok
+}
+
+func (k *Key) KeyName() string {
+	return k.Name
+}
+
+func (k *Key) Allows(endpoint string) bool {
+	if len(k.Endpoints) == 0 {
+		return true
+	}
+
+	for _, e := range k.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k *Key) LinksPerIn(defaultMax uint32) uint32 {
+	if k.MaxLinksPerIn == 0 {
+		return defaultMax
+	}
+
+	return k.MaxLinksPerIn
+}
+
+// StartJob returns false when the key has reached its concurrent jobs limit.
+func (k *Key) StartJob() bool {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.MaxConcurrentJobs != 0 && k.jobs >= k.MaxConcurrentJobs {
+		return false
+	}
+	k.jobs++
+
+	return true
+}
+
+func (k *Key) FinishJob() {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.jobs > 0 {
+		k.jobs--
+	}
+}
+
+// ReserveLinks consumes the daily links quota (UTC day) and returns false when it is exceeded.
+func (k *Key) ReserveLinks(n uint32) bool {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.DailyLinkQuota == 0 {
+		return true
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	if k.day != today {
+		k.day = today
+		k.linksUsed = 0
+	}
+
+	if k.linksUsed+n > k.DailyLinkQuota {
+		return false
+	}
+	k.linksUsed += n
+
+	return true
+}
+
+// ReleaseLinks returns the links reserved for a job which has not started to the quota of the day.
+func (k *Key) ReleaseLinks(n uint32) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.DailyLinkQuota == 0 || k.day != time.Now().UTC().Format(time.DateOnly) {
+		return
+	}
+
+	k.linksUsed -= min(n, k.linksUsed)
+}
@@ -0,0 +1,105 @@
+package apikeys
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	name := "./keys.json"
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "should load valid API keys",
+			content: `[{"name": "a", "key": "secret-a"}, {"name": "b", "key": "secret-b", "endpoints": ["links.get"]}]`,
+			wantErr: false,
+		},
+		{
+			name:    "should fail for duplicated keys",
+			content: `[{"name": "a", "key": "secret"}, {"name": "b", "key": "secret"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an unknown endpoint",
+			content: `[{"name": "a", "key": "secret", "endpoints": ["foo"]}]`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for a key without name",
+			content: `[{"key": "secret"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		//set up
+		if err := os.WriteFile(name, []byte(tt.content), 0644); err != nil {
+			t.Errorf("failed to write %q: %s", name, err)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(name); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := os.Remove(name); err != nil {
+		t.Errorf("rm %q failed: %s", name, err)
+	}
+}
+
+func TestKey_Limits(t *testing.T) {
+	k := &Key{
+		Name:              "a",
+		Key:               "secret",
+		Endpoints:         []string{EndpointLinksCreate},
+		MaxConcurrentJobs: 1,
+		DailyLinkQuota:    3,
+	}
+
+	t.Run("should allow only the listed endpoints", func(t *testing.T) {
+		if !k.Allows(EndpointLinksCreate) {
+			t.Errorf("Allows(%q) = false, want true", EndpointLinksCreate)
+		}
+		if k.Allows(EndpointAdminPurge) {
+			t.Errorf("Allows(%q) = true, want false", EndpointAdminPurge)
+		}
+	})
+
+	t.Run("should limit concurrent jobs", func(t *testing.T) {
+		if !k.StartJob() {
+			t.Error("StartJob() = false, want true")
+		}
+		if k.StartJob() {
+			t.Error("StartJob() = true, want false when the limit is reached")
+		}
+		k.FinishJob()
+		if !k.StartJob() {
+			t.Error("StartJob() = false, want true after the job is finished")
+		}
+	})
+
+	t.Run("should limit daily links", func(t *testing.T) {
+		if !k.ReserveLinks(2) {
+			t.Error("ReserveLinks(2) = false, want true")
+		}
+		if k.ReserveLinks(2) {
+			t.Error("ReserveLinks(2) = true, want false when the quota is exceeded")
+		}
+		if !k.ReserveLinks(1) {
+			t.Error("ReserveLinks(1) = false, want true")
+		}
+	})
+
+	t.Run("should return released links to the quota", func(t *testing.T) {
+		k.ReleaseLinks(2)
+		if !k.ReserveLinks(2) {
+			t.Error("ReserveLinks(2) = false, want true after the links are released")
+		}
+		if k.ReserveLinks(1) {
+			t.Error("ReserveLinks(1) = true, want false when the quota is exceeded")
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+// cacheableStatuses are the statuses cacheable by default (RFC 9111, section 4.2.2).
+var cacheableStatuses = map[int]struct{}{
+	http.StatusOK:                   {},
+	http.StatusNonAuthoritativeInfo: {},
+	http.StatusNoContent:            {},
+	http.StatusMultipleChoices:      {},
+	http.StatusMovedPermanently:     {},
+	http.StatusNotFound:             {},
+	http.StatusMethodNotAllowed:     {},
+	http.StatusGone:                 {},
+	http.StatusRequestURITooLong:    {},
+	http.StatusNotImplemented:       {},
+	http.StatusPermanentRedirect:    {},
+}
+
+// Cache is a shared HTTP cache in front of an HTTP client. Fresh responses are served from memory,
+// stale ones are revalidated with ETag or Last-Modified. Entries are evicted in LRU order
+// when their total size exceeds maxBytes.
+type Cache struct {
+	inner    contracts.HTTPClient
+	maxBytes int64
+	now      func() time.Time
+	m        sync.Mutex
+	size     int64
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type entry struct {
+	link       string
+	output     models.Output
+	size       int64
+	storedAt   time.Time     // when the response was received or revalidated
+	initialAge time.Duration // age of the response when it was received
+	lifetime   time.Duration
+	noCache    bool // the response must be revalidated before every use
+}
+
+func New(inner contracts.HTTPClient, maxBytes int64) *Cache {
+	return &Cache{
+		inner:    inner,
+		maxBytes: maxBytes,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get serves a fresh cached response or fetches the link, revalidating a stale cached response when possible.
+// Cached responses keep the latency of the upstream request which fetched or revalidated them.
+// The cache is not read when the context has the "noCache" flag, the fetched response is still stored.
+func (c *Cache) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
+	if header != nil {
+		// conditional requests of the caller are not cached
+		return c.inner.Get(ctx, link, header)
+	}
+
+	bypass, _ := ctx.Value(contracts.ContextKey("noCache")).(bool)
+
+	var cached *entry
+	if !bypass {
+		cached = c.lookup(link)
+	}
+
+	if cached != nil && !cached.noCache && c.age(cached) < cached.lifetime {
+		return c.served(cached), nil
+	}
+
+	var conditional http.Header
+	if cached != nil {
+		conditional = validators(cached.output.Header)
+	}
+
+	output, err := c.inner.Get(ctx, link, conditional)
+	if err != nil {
+		return output, err
+	}
+
+	if output.StatusCode == http.StatusNotModified && cached != nil {
+		// the revalidated response keeps the latency of the revalidation
+		updated := withHeader(cached.output, output.Header)
+		updated.Latency = output.Latency
+		revalidated := c.store(link, updated)
+		if revalidated == nil {
+			updated.Cached = true
+
+			return updated, nil
+		}
+
+		return c.served(revalidated), nil
+	}
+
+	c.store(link, output)
+
+	return output, nil
+}
+
+// Size returns the total size of the cached entries.
+func (c *Cache) Size() int64 {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.size
+}
+
+func (c *Cache) lookup(link string) *entry {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[link]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+
+	return e.Value.(*entry)
+}
+
+// store caches the output if allowed and returns the new entry.
+func (c *Cache) store(link string, output models.Output) *entry {
+	e := c.newEntry(link, output)
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.remove(link)
+	if e == nil || e.size > c.maxBytes {
+		return nil
+	}
+
+	c.entries[link] = c.lru.PushFront(e)
+	c.size += e.size
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back().Value.(*entry).link)
+	}
+
+	return e
+}
+
+func (c *Cache) remove(link string) {
+	e, ok := c.entries[link]
+	if !ok {
+		return
+	}
+
+	c.lru.Remove(e)
+	delete(c.entries, link)
+	c.size -= e.Value.(*entry).size
+}
+
+func (c *Cache) newEntry(link string, output models.Output) *entry {
+	if _, ok := cacheableStatuses[output.StatusCode]; !ok {
+		return nil
+	}
+
+	directives := parseCacheControl(output.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return nil
+	}
+	if _, ok := directives["private"]; ok {
+		return nil
+	}
+	if output.Header.Get("Vary") == "*" {
+		return nil
+	}
+
+	now := c.now()
+	e := &entry{
+		link:     link,
+		output:   output,
+		size:     int64(len(link) + len(output.Body)),
+		storedAt: now,
+		lifetime: lifetime(output.Header, directives, now),
+	}
+	_, e.noCache = directives["no-cache"]
+	for key, values := range output.Header {
+		e.size += int64(len(key))
+		for _, v := range values {
+			e.size += int64(len(v))
+		}
+	}
+
+	if age, err := strconv.ParseInt(output.Header.Get("Age"), 10, 64); err == nil && age > 0 {
+		e.initialAge = time.Duration(age) * time.Second
+	}
+
+	// responses without freshness information and validators are useless in cache
+	if e.lifetime <= 0 && len(validators(output.Header)) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (c *Cache) age(e *entry) time.Duration {
+	return e.initialAge + c.now().Sub(e.storedAt)
+}
+
+func (c *Cache) served(e *entry) models.Output {
+	output := e.output
+	output.Cached = true
+	output.Age = int64(c.age(e) / time.Second)
+
+	return output
+}
+
+// lifetime returns the freshness lifetime of a response, shared cache directives take precedence.
+func lifetime(header http.Header, directives map[string]string, now time.Time) time.Duration {
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || seconds < 0 {
+				return 0
+			}
+
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if expires := header.Get("Expires"); len(expires) != 0 {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+
+		return expiresAt.Sub(date)
+	}
+
+	return 0
+}
+
+func validators(header http.Header) http.Header {
+	conditional := make(http.Header)
+	if etag := header.Get("ETag"); len(etag) != 0 {
+		conditional.Set("If-None-Match", etag)
+	}
+	if lastModified := header.Get("Last-Modified"); len(lastModified) != 0 {
+		conditional.Set("If-Modified-Since", lastModified)
+	}
+
+	return conditional
+}
+
+// withHeader updates the stored response header with the header of a 304 response.
+func withHeader(output models.Output, header http.Header) models.Output {
+	updated := output.Header.Clone()
+	if updated == nil {
+		updated = make(http.Header)
+	}
+	for key, values := range header {
+		updated[key] = values
+	}
+	output.Header = updated
+
+	return output
+}
+
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if len(name) == 0 {
+			continue
+		}
+
+		directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+	}
+
+	return directives
+}
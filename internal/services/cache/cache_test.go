@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+type httpClientMock struct {
+	responses map[string]models.Output
+	requests  []http.Header
+}
+
+func (c *httpClientMock) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
+	c.requests = append(c.requests, header)
+	output := c.responses[link]
+	if header.Get("If-None-Match") == output.Header.Get("ETag") && len(header.Get("If-None-Match")) != 0 {
+		return models.Output{
+			URL:        link,
+			StatusCode: http.StatusNotModified,
+			Header:     http.Header{"Cache-Control": {"max-age=60"}},
+			Latency:    20 * time.Millisecond,
+		}, nil
+	}
+
+	return output, nil
+}
+
+func TestCache_Get(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       http.Header
+		ctx          context.Context
+		wait         time.Duration
+		wantRequests int
+		wantCached   bool
+		wantAge      int64
+		wantLatency  time.Duration
+		wantIfMatch  string
+	}{
+		{
+			name:         "should serve a fresh response from cache",
+			header:       http.Header{"Cache-Control": {"max-age=60"}},
+			ctx:          context.Background(),
+			wait:         10 * time.Second,
+			wantRequests: 1,
+			wantCached:   true,
+			wantAge:      10,
+			wantLatency:  300 * time.Millisecond,
+		},
+		{
+			name:         "should take the age of the response into account",
+			header:       http.Header{"Cache-Control": {"max-age=60"}, "Age": {"55"}},
+			ctx:          context.Background(),
+			wait:         10 * time.Second,
+			wantRequests: 2,
+			wantCached:   false,
+			wantLatency:  300 * time.Millisecond,
+		},
+		{
+			name:         "should revalidate a stale response with its ETag",
+			header:       http.Header{"Cache-Control": {"max-age=1"}, "Etag": {`"v1"`}},
+			ctx:          context.Background(),
+			wait:         10 * time.Second,
+			wantRequests: 2,
+			wantCached:   true,
+			wantAge:      0,
+			wantLatency:  20 * time.Millisecond,
+			wantIfMatch:  `"v1"`,
+		},
+		{
+			name:         "should not store a response with no-store directive",
+			header:       http.Header{"Cache-Control": {"no-store, max-age=60"}},
+			ctx:          context.Background(),
+			wantRequests: 2,
+			wantCached:   false,
+			wantLatency:  300 * time.Millisecond,
+		},
+		{
+			name:         "should honour Expires",
+			header:       http.Header{"Date": {"Mon, 19 Oct 2026 10:00:00 GMT"}, "Expires": {"Mon, 19 Oct 2026 10:01:00 GMT"}},
+			ctx:          context.Background(),
+			wait:         30 * time.Second,
+			wantRequests: 1,
+			wantCached:   true,
+			wantAge:      30,
+			wantLatency:  300 * time.Millisecond,
+		},
+		{
+			name:         "should not serve cached responses when bypass is requested",
+			header:       http.Header{"Cache-Control": {"max-age=60"}},
+			ctx:          context.WithValue(context.Background(), contracts.ContextKey("noCache"), true),
+			wantRequests: 2,
+			wantCached:   false,
+			wantLatency:  300 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//set up
+			link := "https://example.com"
+			inner := &httpClientMock{
+				responses: map[string]models.Output{
+					link: {URL: link, StatusCode: http.StatusOK, Body: "body", Header: tt.header, Latency: 300 * time.Millisecond},
+				},
+			}
+			now := time.Now()
+			c := New(inner, 1024)
+			c.now = func() time.Time {
+				return now
+			}
+
+			if _, err := c.Get(tt.ctx, link, nil); err != nil {
+				t.Errorf("Get() error = %v", err)
+				return
+			}
+			now = now.Add(tt.wait)
+			got, err := c.Get(tt.ctx, link, nil)
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+				return
+			}
+
+			if len(inner.requests) != tt.wantRequests {
+				t.Errorf("Get() sent %d requests, want %d", len(inner.requests), tt.wantRequests)
+			}
+			if got.Cached != tt.wantCached || got.Age != tt.wantAge {
+				t.Errorf("Get() cached = %v, age = %d, want %v, %d", got.Cached, got.Age, tt.wantCached, tt.wantAge)
+			}
+			if got.Latency != tt.wantLatency {
+				t.Errorf("Get() latency = %s, want %s", got.Latency, tt.wantLatency)
+			}
+			if got.Body != "body" || got.StatusCode != http.StatusOK {
+				t.Errorf("Get() = %d %q, want the original response", got.StatusCode, got.Body)
+			}
+			if len(tt.wantIfMatch) != 0 && inner.requests[1].Get("If-None-Match") != tt.wantIfMatch {
+				t.Errorf("If-None-Match = %q, want %q", inner.requests[1].Get("If-None-Match"), tt.wantIfMatch)
+			}
+		})
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	header := http.Header{"Cache-Control": {"max-age=60"}}
+	inner := &httpClientMock{
+		responses: map[string]models.Output{
+			"a": {URL: "a", StatusCode: http.StatusOK, Body: "12345", Header: header},
+			"b": {URL: "b", StatusCode: http.StatusOK, Body: "12345", Header: header},
+			"c": {URL: "c", StatusCode: http.StatusOK, Body: "12345", Header: header},
+		},
+	}
+	entrySize := int64(len("a") + len("12345") + len("Cache-Control") + len("max-age=60"))
+	c := New(inner, 2*entrySize)
+
+	for _, link := range []string{"a", "b", "a", "c"} {
+		if _, err := c.Get(context.Background(), link, nil); err != nil {
+			t.Errorf("Get() error = %v", err)
+			return
+		}
+	}
+
+	t.Run("should evict the least recently used entry", func(t *testing.T) {
+		if c.Size() != 2*entrySize {
+			t.Errorf("Size() = %d, want %d", c.Size(), 2*entrySize)
+		}
+		for _, tt := range []struct {
+			link       string
+			wantCached bool
+		}{
+			{link: "a", wantCached: true},
+			{link: "c", wantCached: true},
+			{link: "b", wantCached: false},
+		} {
+			if got, _ := c.Get(context.Background(), tt.link, nil); got.Cached != tt.wantCached {
+				t.Errorf("Get(%q) cached = %v, want %v", tt.link, got.Cached, tt.wantCached)
+			}
+		}
+	})
+}
@@ -1,34 +1,48 @@
 package cleaner
 
 import (
-	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
 )
 
 type Cleaner struct {
 	storeTimeout time.Duration
-	storePath    string
+	state        contracts.State
+	store        contracts.Store
 	logger       *slog.Logger
 	done         chan struct{}
+	alive        atomic.Bool
+	shutdown     sync.Once
 }
 
 func New(
 	storeTimeout time.Duration,
-	storePath string,
+	state contracts.State,
+	store contracts.Store,
 	logger *slog.Logger,
 ) *Cleaner {
 	c := Cleaner{
 		storeTimeout: storeTimeout,
-		storePath:    storePath,
+		state:        state,
+		store:        store,
 		logger:       logger,
 		done:         make(chan struct{}),
 	}
 
 	ticker := time.NewTicker(storeTimeout)
+	c.alive.Store(true)
 	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.logger.Error("cleaner stopped", slog.Any("panic", r))
+			}
+			c.alive.Store(false)
+			ticker.Stop()
+		}()
+
 		for {
 			select {
 			case <-c.done:
@@ -43,34 +57,47 @@ func New(
 }
 
 func (c *Cleaner) do() {
-	c.logger.Info("Cleaner started")
+	c.logger.Info("cleaner started")
 
-	entries, err := os.ReadDir(c.storePath)
+	entries, err := c.store.List()
 	if err != nil {
-		c.logger.Error(fmt.Sprintf("Cleaner failed to list %q: %s", c.storePath, err))
+		c.logger.Error("cleaner failed to list stored results", slog.Any("error", err))
 		return
 	}
 
 	for _, e := range entries {
-		fileInfo, err := e.Info()
-		if err != nil {
-			c.logger.Error(fmt.Sprintf("Cleaner failed to get %q file info: %s", e.Name(), err))
+		// records of the jobs still in progress are kept, however long they run
+		if c.state.Check(e.ID) {
 			continue
 		}
 
-		if time.Since(fileInfo.ModTime()) < c.storeTimeout {
+		// records without results, e.g. left by a crash, expire since their jobs were created
+		storedAt := e.ModTime
+		if !e.HasResult {
+			if job, err := c.store.LoadJob(e.ID); err == nil {
+				storedAt = job.CreatedAt
+			}
+		}
+		if time.Since(storedAt) < c.storeTimeout {
 			continue
 		}
 
-		name := filepath.Join(c.storePath, e.Name())
-		if err := os.Remove(name); err != nil {
-			c.logger.Error(fmt.Sprintf("Cleaner failed to remove %q: %s", e.Name(), err))
+		// the store waits for active reads of the result to finish
+		if _, err := c.store.Remove(e.ID); err != nil {
+			c.logger.Error("cleaner failed to remove result", slog.String("job_id", e.ID), slog.Any("error", err))
 		}
 	}
 
-	c.logger.Info("Cleaner cycle finished")
+	c.logger.Info("cleaner cycle finished")
+}
+
+// Alive returns false when the cleaner goroutine has stopped.
+func (c *Cleaner) Alive() bool {
+	return c.alive.Load()
 }
 
 func (c *Cleaner) Shutdown() {
-	c.done <- struct{}{}
+	c.shutdown.Do(func() {
+		close(c.done)
+	})
 }
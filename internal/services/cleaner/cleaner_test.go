@@ -1,11 +1,17 @@
 package cleaner
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/internal/services/progress"
+	"yegorov-boris/affise-test-task/internal/services/store"
 )
 
 func TestNew(t *testing.T) {
@@ -18,16 +24,16 @@ func TestNew(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want string
+		want []string
 	}{
 		{
-			name: "should clean outdated files",
+			name: "should clean outdated files and job records and keep the jobs in progress",
 			args: args{
 				storeTimeout: 2 * time.Second,
 				storePath:    storePath,
 				logger:       slog.New(slog.NewTextHandler(os.Stdout, nil)),
 			},
-			want: "2.json",
+			want: []string{"1.job", "3.json"},
 		},
 	}
 	for _, tt := range tests {
@@ -37,14 +43,40 @@ func TestNew(t *testing.T) {
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
-			c := New(tt.args.storeTimeout, tt.args.storePath, tt.args.logger)
+			state, err := progress.New(tt.args.storePath, false)
+			if err != nil {
+				t.Errorf("progress.New() error = %v", err)
+				return
+			}
+			resultsStore := store.New(tt.args.logger, tt.args.storePath)
+			c := New(tt.args.storeTimeout, state, resultsStore, tt.args.logger)
 
-			for _, id := range []string{"1", "2"} {
+			// the job 1 is in progress, the job 10 is left without a result by a crash
+			inProgressID, _, err := state.Start(context.Background())
+			if err != nil {
+				t.Errorf("Start() error = %v", err)
+				return
+			}
+			jobs := []models.Job{
+				{ID: inProgressID, CreatedAt: time.Now().Add(-time.Hour)},
+				{ID: "10", CreatedAt: time.Now().Add(-time.Hour)},
+			}
+			for _, job := range jobs {
+				if err := resultsStore.SaveJob(job); err != nil {
+					t.Errorf("SaveJob() error = %v", err)
+				}
+			}
+			for _, id := range []string{"2", "3"} {
 				name := fmt.Sprintf("%s%s.json", storePath, id)
 				_, err := os.Create(name)
 				if err != nil {
 					t.Errorf("failed to create %q: %s", name, err)
 				}
+				// the ticker starts before the files are created, so they are made a bit older
+				modTime := time.Now().Add(-100 * time.Millisecond)
+				if err := os.Chtimes(name, modTime, modTime); err != nil {
+					t.Errorf("failed to change times of %q: %s", name, err)
+				}
 				time.Sleep(time.Second)
 			}
 			time.Sleep(100 * time.Millisecond)
@@ -52,16 +84,19 @@ func TestNew(t *testing.T) {
 			c.Shutdown()
 			time.Sleep(2100 * time.Millisecond)
 
-			entries, err := os.ReadDir(c.storePath)
+			entries, err := os.ReadDir(tt.args.storePath)
 			if err != nil {
 				t.Errorf("failed to list dir %q: %s", storePath, err)
 			}
-			if len(entries) != 1 || entries[0].Name() != tt.want {
-				actualFileNames := []string{}
-				for _, e := range entries {
+			actualFileNames := []string{}
+			for _, e := range entries {
+				if ext := filepath.Ext(e.Name()); ext == ".json" || ext == ".job" {
 					actualFileNames = append(actualFileNames, e.Name())
 				}
-				t.Errorf("Expected the only file %q after cleaning - got %v", tt.want, actualFileNames)
+			}
+			state.Finish(inProgressID)
+			if !reflect.DeepEqual(actualFileNames, tt.want) {
+				t.Errorf("Expected the files %v after cleaning - got %v", tt.want, actualFileNames)
 			}
 		})
 	}
@@ -0,0 +1,118 @@
+package coalescer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+// Coalescer shares one upstream request between the concurrent requests of the same link with the same header.
+// The shared request is canceled only when all the waiting callers are canceled.
+// Values of the first caller context, e.g. the request ID and the trace, are used by the shared request.
+type Coalescer struct {
+	inner contracts.HTTPClient
+	m     sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	output  models.Output
+	err     error
+}
+
+func New(inner contracts.HTTPClient) *Coalescer {
+	return &Coalescer{
+		inner: inner,
+		calls: make(map[string]*call),
+	}
+}
+
+func (c *Coalescer) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
+	k := key(link, header)
+
+	c.m.Lock()
+	cl, ok := c.calls[k]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &call{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		c.calls[k] = cl
+		go c.fetch(fetchCtx, k, cl, link, header)
+	}
+	cl.waiters++
+	c.m.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.output, cl.err
+	case <-ctx.Done():
+		c.m.Lock()
+		cl.waiters--
+		if cl.waiters == 0 {
+			cl.cancel()
+			c.forget(k, cl)
+		}
+		c.m.Unlock()
+
+		return models.Output{}, fmt.Errorf("failed to get response: %w", ctx.Err())
+	}
+}
+
+// InFlight returns the number of shared requests in progress.
+func (c *Coalescer) InFlight() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.calls)
+}
+
+func (c *Coalescer) fetch(ctx context.Context, k string, cl *call, link string, header http.Header) {
+	defer cl.cancel()
+
+	cl.output, cl.err = c.inner.Get(ctx, link, header)
+
+	c.m.Lock()
+	c.forget(k, cl)
+	c.m.Unlock()
+
+	close(cl.done)
+}
+
+// forget lets the next callers start a new request, it must be called under the lock.
+func (c *Coalescer) forget(k string, cl *call) {
+	if c.calls[k] == cl {
+		delete(c.calls, k)
+	}
+}
+
+// key identifies requests by method, link and header.
+func key(link string, header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(http.MethodGet)
+	sb.WriteString(" ")
+	sb.WriteString(link)
+	for _, name := range names {
+		sb.WriteString("\n")
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(strings.Join(header[name], ", "))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package coalescer
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+type httpClientMock struct {
+	calls    atomic.Int32
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func (c *httpClientMock) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
+	c.calls.Add(1)
+	select {
+	case <-c.release:
+		return models.Output{URL: link, StatusCode: http.StatusOK}, nil
+	case <-ctx.Done():
+		close(c.canceled)
+		return models.Output{}, ctx.Err()
+	}
+}
+
+func TestCoalescer_Get(t *testing.T) {
+	t.Run("should share one request between concurrent callers", func(t *testing.T) {
+		inner := &httpClientMock{release: make(chan struct{}), canceled: make(chan struct{})}
+		c := New(inner)
+
+		var wg sync.WaitGroup
+		wg.Add(3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				defer wg.Done()
+				if output, err := c.Get(context.Background(), "https://example.com", nil); err != nil || output.StatusCode != http.StatusOK {
+					t.Errorf("Get() = %v, %v", output, err)
+				}
+			}()
+		}
+		for c.waiters("https://example.com") < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		close(inner.release)
+		wg.Wait()
+
+		if calls := inner.calls.Load(); calls != 1 {
+			t.Errorf("upstream got %d requests, want 1", calls)
+		}
+		if c.InFlight() != 0 {
+			t.Errorf("InFlight() = %d, want 0", c.InFlight())
+		}
+	})
+
+	t.Run("should cancel the shared request when all callers are canceled", func(t *testing.T) {
+		inner := &httpClientMock{release: make(chan struct{}), canceled: make(chan struct{})}
+		c := New(inner)
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		for _, ctx := range []context.Context{ctx1, ctx2} {
+			go func(ctx context.Context) {
+				defer wg.Done()
+				_, _ = c.Get(ctx, "https://example.com", nil)
+			}(ctx)
+		}
+		for c.waiters("https://example.com") < 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		cancel1()
+		select {
+		case <-inner.canceled:
+			t.Error("the shared request is canceled while a caller is waiting")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		cancel2()
+		select {
+		case <-inner.canceled:
+		case <-time.After(time.Second):
+			t.Error("the shared request is not canceled after all callers are canceled")
+		}
+		wg.Wait()
+	})
+
+	t.Run("should not share requests with different headers", func(t *testing.T) {
+		if key("https://example.com", nil) == key("https://example.com", http.Header{"If-None-Match": {`"v1"`}}) {
+			t.Error("key() is the same for different headers")
+		}
+	})
+}
+
+func (c *Coalescer) waiters(link string) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if cl, ok := c.calls[key(link, nil)]; ok {
+		return cl.waiters
+	}
+
+	return 0
+}
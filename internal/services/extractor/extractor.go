@@ -0,0 +1,72 @@
+package extractor
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/extract"
+)
+
+// Extractor pulls the title, meta tags, links and visible text out of HTML bodies
+// and the values selected by dotted paths out of JSON bodies.
+type Extractor struct{}
+
+func New() *Extractor {
+	return &Extractor{}
+}
+
+// Extract fills the extracted data of the output. Bodies which are not HTML or JSON are skipped.
+func (e *Extractor) Extract(output models.Output, options models.Extraction) models.Output {
+	if options.IsEmpty() {
+		return output
+	}
+
+	extracted := new(models.Extracted)
+	if options.HTML() && isHTML(output) {
+		base, _ := url.Parse(output.URL)
+		doc := extract.ParseHTML(output.Body, base)
+		if options.Title {
+			extracted.Title = doc.Title
+		}
+		if options.Meta {
+			extracted.Meta = doc.Meta
+		}
+		if options.Links {
+			extracted.Links = doc.Links
+		}
+		if options.Text {
+			extracted.Text = doc.Text
+		}
+	}
+
+	if len(options.JSONPaths) != 0 {
+		// the content type is not checked, since many APIs respond with JSON as text/plain
+		if selected, err := extract.SelectJSON([]byte(output.Body), options.JSONPaths); err == nil && len(selected) != 0 {
+			extracted.JSON = selected
+		}
+	}
+
+	if options.HTML() || len(options.JSONPaths) != 0 {
+		output.Extracted = extracted
+	}
+	if options.DropBody {
+		output.Body = ""
+	}
+
+	return output
+}
+
+func isHTML(output models.Output) bool {
+	contentType := output.Header.Get("Content-Type")
+	if len(contentType) == 0 {
+		contentType = http.DetectContentType([]byte(output.Body))
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
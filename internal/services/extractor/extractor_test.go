@@ -0,0 +1,80 @@
+package extractor
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+func TestExtractor_Extract(t *testing.T) {
+	htmlOutput := models.Output{
+		URL:        "https://example.com/",
+		StatusCode: http.StatusOK,
+		Body:       `<html><head><title>Foo</title></head><body><a href="/bar">Bar</a></body></html>`,
+	}
+	jsonOutput := models.Output{
+		URL:        "https://example.com/api",
+		StatusCode: http.StatusOK,
+		Body:       `{"foo": {"bar": "baz"}}`,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+	}
+	tests := []struct {
+		name    string
+		output  models.Output
+		options models.Extraction
+		want    models.Output
+	}{
+		{
+			name:   "should keep the output when nothing is requested",
+			output: htmlOutput,
+			want:   htmlOutput,
+		},
+		{
+			name:    "should extract from a sniffed HTML body",
+			output:  htmlOutput,
+			options: models.Extraction{Title: true, Links: true, Text: true},
+			want: models.Output{
+				URL:        htmlOutput.URL,
+				StatusCode: htmlOutput.StatusCode,
+				Body:       htmlOutput.Body,
+				Extracted: &models.Extracted{
+					Title: "Foo",
+					Links: []string{"https://example.com/bar"},
+					Text:  "Bar",
+				},
+			},
+		},
+		{
+			name:    "should not parse JSON as HTML",
+			output:  jsonOutput,
+			options: models.Extraction{Title: true, Text: true},
+			want: models.Output{
+				URL:        jsonOutput.URL,
+				StatusCode: jsonOutput.StatusCode,
+				Body:       jsonOutput.Body,
+				Header:     jsonOutput.Header,
+				Extracted:  &models.Extracted{},
+			},
+		},
+		{
+			name:    "should select JSON values and drop the body",
+			output:  jsonOutput,
+			options: models.Extraction{JSONPaths: []string{"foo.bar", "missing"}, DropBody: true},
+			want: models.Output{
+				URL:        jsonOutput.URL,
+				StatusCode: jsonOutput.StatusCode,
+				Header:     jsonOutput.Header,
+				Extracted:  &models.Extracted{JSON: map[string]any{"foo.bar": "baz"}},
+			},
+		},
+	}
+	e := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Extract(tt.output, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
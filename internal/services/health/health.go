@@ -0,0 +1,65 @@
+package health
+
+import (
+	"sync"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+type (
+	// Checker runs named checks of the subsystems, all of them have to pass for the service to be ready.
+	Checker struct {
+		m      sync.Mutex
+		names  []string
+		checks map[string]Check
+	}
+
+	Check = func() error
+)
+
+func New() *Checker {
+	return &Checker{
+		checks: make(map[string]Check),
+	}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+func (c *Checker) Check() models.Health {
+	c.m.Lock()
+	names := append([]string(nil), c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.m.Unlock()
+
+	result := models.Health{
+		Status: models.HealthStatusOK,
+		Checks: make(map[string]models.CheckResult, len(names)),
+	}
+	for i, name := range names {
+		if err := checks[i](); err != nil {
+			result.Status = models.HealthStatusFail
+			result.Checks[name] = models.CheckResult{
+				Status: models.HealthStatusFail,
+				Error:  err.Error(),
+			}
+
+			continue
+		}
+
+		result.Checks[name] = models.CheckResult{
+			Status: models.HealthStatusOK,
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+func TestChecker_Check(t *testing.T) {
+	ok := func() error {
+		return nil
+	}
+	fail := func() error {
+		return errors.New("broken")
+	}
+	tests := []struct {
+		name   string
+		checks map[string]Check
+		want   models.Health
+	}{
+		{
+			name: "should be ok when all the checks pass",
+			checks: map[string]Check{
+				"a": ok,
+				"b": ok,
+			},
+			want: models.Health{
+				Status: models.HealthStatusOK,
+				Checks: map[string]models.CheckResult{
+					"a": {Status: models.HealthStatusOK},
+					"b": {Status: models.HealthStatusOK},
+				},
+			},
+		},
+		{
+			name: "should fail when any of the checks fails",
+			checks: map[string]Check{
+				"a": ok,
+				"b": fail,
+			},
+			want: models.Health{
+				Status: models.HealthStatusFail,
+				Checks: map[string]models.CheckResult{
+					"a": {Status: models.HealthStatusOK},
+					"b": {Status: models.HealthStatusFail, Error: "broken"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			for name, check := range tt.checks {
+				c.Add(name, check)
+			}
+			if got := c.Check(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
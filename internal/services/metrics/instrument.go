@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+const (
+	JobStateSucceeded   = "succeeded"
+	JobStateFailed      = "failed"
+	JobStateCanceled    = "canceled"
+	JobStateInterrupted = "interrupted"
+)
+
+type (
+	httpClient struct {
+		inner    contracts.HTTPClient
+		requests *CounterVec
+		duration *HistogramVec
+	}
+
+	scraper struct {
+		inner    contracts.Scraper
+		jobs     *CounterVec
+		duration *HistogramVec
+	}
+)
+
+// InstrumentHTTPClient counts upstream requests by status class and observes their latency.
+func InstrumentHTTPClient(r *Registry, inner contracts.HTTPClient) contracts.HTTPClient {
+	return &httpClient{
+		inner: inner,
+		requests: r.NewCounterVec(
+			"multiplexer_upstream_requests_total",
+			"Outgoing requests by response status class.",
+			"status_class",
+		),
+		duration: r.NewHistogramVec(
+			"multiplexer_upstream_request_duration_seconds",
+			"Outgoing requests latency.",
+			DefaultBuckets,
+		),
+	}
+}
+
+// InstrumentScraper counts jobs by final state and observes their duration.
+func InstrumentScraper(r *Registry, inner contracts.Scraper) contracts.Scraper {
+	s := &scraper{
+		inner: inner,
+		jobs: r.NewCounterVec(
+			"multiplexer_jobs_total",
+			"Finished jobs by final state.",
+			"state",
+		),
+		duration: r.NewHistogramVec(
+			"multiplexer_job_duration_seconds",
+			"Jobs duration.",
+			DefaultBuckets,
+		),
+	}
+	for _, state := range []string{JobStateSucceeded, JobStateFailed, JobStateCanceled, JobStateInterrupted} {
+		s.jobs.Add(0, state)
+	}
+
+	return s
+}
+
+func (c *httpClient) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
+	start := time.Now()
+	output, err := c.inner.Get(ctx, link, header)
+	c.duration.Observe(time.Since(start).Seconds())
+
+	statusClass := "error"
+	if err == nil {
+		statusClass = fmt.Sprintf("%dxx", output.StatusCode/100)
+	}
+	c.requests.Inc(statusClass)
+
+	return output, err
+}
+
+func (s *scraper) Scrap(ctx context.Context, input models.Input, check models.Check) ([]models.Output, string) {
+	start := time.Now()
+	outputs, errMsg := s.inner.Scrap(ctx, input, check)
+	s.duration.Observe(time.Since(start).Seconds())
+
+	state := JobStateSucceeded
+	if len(errMsg) != 0 {
+		state = JobStateFailed
+		if errors.Is(ctx.Err(), context.Canceled) {
+			state = JobStateCanceled
+		}
+		if errors.Is(context.Cause(ctx), models.ErrInterrupted) {
+			state = JobStateInterrupted
+		}
+	}
+	s.jobs.Inc(state)
+
+	return outputs, errMsg
+}
@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type (
+	// Registry writes the registered metrics in Prometheus text exposition format.
+	Registry struct {
+		m          sync.Mutex
+		collectors []collector
+	}
+
+	collector interface {
+		write(w *bufio.Writer)
+	}
+
+	desc struct {
+		name   string
+		help   string
+		kind   string
+		labels []string
+	}
+
+	CounterVec struct {
+		desc
+		m      sync.Mutex
+		values map[string]float64
+	}
+
+	HistogramVec struct {
+		desc
+		buckets []float64
+		m       sync.Mutex
+		values  map[string]*histogram
+	}
+
+	histogram struct {
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+
+	GaugeFunc struct {
+		desc
+		f func() float64
+	}
+)
+
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]float64),
+	}
+	r.register(c)
+
+	return c
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+	r.register(h)
+
+	return h
+}
+
+// NewGaugeFunc registers a gauge which value is computed on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: desc{name: name, help: help, kind: "gauge"},
+		f:    f,
+	}
+	r.register(g)
+
+	return g
+}
+
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.m.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.m.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+func (r *Registry) register(c collector) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// Add increases the counter with the given label values, they must match the label names.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := c.key(labelValues)
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.values[key] += v
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.header(w)
+	for _, key := range sortedKeys(c.values) {
+		writeSample(w, c.name, key, c.values[key])
+	}
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogram{
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.header(w)
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		for i, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", joinLabels(key, fmt.Sprintf("le=%q", formatFloat(upper))), float64(s.counts[i]))
+		}
+		writeSample(w, h.name+"_bucket", joinLabels(key, `le="+Inf"`), float64(s.count))
+		writeSample(w, h.name+"_sum", key, s.sum)
+		writeSample(w, h.name+"_count", key, float64(s.count))
+	}
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w)
+	writeSample(w, g.name, "", g.f())
+}
+
+func (d *desc) header(w *bufio.Writer) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// key renders the label pairs, it is used both as a map key and in the output.
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metric %q expects %d label values, got %d", d.name, len(d.labels), len(labelValues)))
+	}
+
+	pairs := make([]string, len(d.labels))
+	for i, name := range d.labels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, escapeLabel(labelValues[i]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	if len(labels) != 0 {
+		labels = "{" + labels + "}"
+	}
+
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(v))
+}
+
+func joinLabels(labels ...string) string {
+	nonEmpty := labels[:0:0]
+	for _, l := range labels {
+		if len(l) != 0 {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+
+	return strings.Join(nonEmpty, ",")
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
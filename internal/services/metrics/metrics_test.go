@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_requests_total", "Test requests.", "code")
+	histogram := r.NewHistogramVec("test_duration_seconds", "Test duration.", []float64{0.1, 1})
+	r.NewGaugeFunc("test_in_flight", "Test gauge.", func() float64 {
+		return 3
+	})
+	counter.Inc("2xx")
+	counter.Add(2, `a"b`)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	want := strings.Join([]string{
+		"# HELP test_requests_total Test requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{code="2xx"} 1`,
+		`test_requests_total{code="a\"b"} 2`,
+		"# HELP test_duration_seconds Test duration.",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="0.1"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 5.55",
+		"test_duration_seconds_count 3",
+		"# HELP test_in_flight Test gauge.",
+		"# TYPE test_in_flight gauge",
+		"test_in_flight 3",
+		"",
+	}, "\n")
+
+	t.Run("should write metrics in Prometheus text format", func(t *testing.T) {
+		var b strings.Builder
+		n, err := r.WriteTo(&b)
+		if err != nil {
+			t.Errorf("WriteTo() error = %v", err)
+			return
+		}
+		if b.String() != want {
+			t.Errorf("WriteTo() got\n%s\nwant\n%s", b.String(), want)
+		}
+		if n != int64(len(want)) {
+			t.Errorf("WriteTo() n = %d, want %d", n, len(want))
+		}
+	})
+}
@@ -2,6 +2,9 @@ package progress
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -11,15 +14,27 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"yegorov-boris/affise-test-task/internal/models"
 )
 
+// lastIDFile keeps the high-water mark of sequential IDs,
+// so IDs are not reused after the stored results are removed.
+const lastIDFile = ".last_id"
+
 type State struct {
-	uid   atomic.Uint64
-	len   atomic.Int32
-	state sync.Map
+	storePath string
+	randomIDs bool
+	m         sync.Mutex
+	uid       uint64
+	len       atomic.Int32
+	wg        sync.WaitGroup
+	state     sync.Map
+	// closing guards closed, so no jobs are added to wg once waiting on shutdown begins
+	closing sync.RWMutex
+	closed  bool
 }
 
-func New(storePath string) (*State, error) {
+func New(storePath string, randomIDs bool) (*State, error) {
 	var maxID uint64
 
 	if err := os.MkdirAll(storePath, fs.ModeDir); err != nil {
@@ -31,7 +46,10 @@ func New(storePath string) (*State, error) {
 		return nil, fmt.Errorf("failed to list %q: %w", storePath, err)
 	}
 
-	s := new(State)
+	s := &State{
+		storePath: storePath,
+		randomIDs: randomIDs,
+	}
 
 	for _, e := range entries {
 		name := e.Name()
@@ -45,42 +63,186 @@ func New(storePath string) (*State, error) {
 		}
 	}
 
-	s.uid.Store(maxID)
+	lastID, err := s.loadLastID()
+	if err != nil {
+		return nil, err
+	}
+
+	s.uid = max(maxID, lastID)
 
 	return s, nil
 }
 
-func (s *State) Start() (uint64, context.Context) {
-	id := s.uid.Add(1)
-	ctx, cancel := context.WithCancel(context.Background())
+// Start registers a new job. The job context keeps the parent values (request ID, API key etc.),
+// but is not canceled along with the parent.
+// It fails with models.ErrShuttingDown after Close.
+func (s *State) Start(parent context.Context) (string, context.Context, error) {
+	s.closing.RLock()
+	defer s.closing.RUnlock()
+
+	if s.closed {
+		return "", nil, models.ErrShuttingDown
+	}
+
+	id, err := s.nextID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(parent))
 	s.state.Store(id, cancel)
 	s.len.Add(1)
+	s.wg.Add(1)
 
-	return id, ctx
+	return id, ctx, nil
 }
 
-func (s *State) Finish(id uint64) {
+func (s *State) Finish(id string) {
 	s.state.Delete(id)
 	s.len.Add(-1)
+	s.wg.Done()
 }
 
-func (s *State) Check(id uint64) bool {
+func (s *State) Check(id string) bool {
 	_, ok := s.state.Load(id)
 
 	return ok
 }
 
-func (s *State) Cancel(id uint64) bool {
+func (s *State) Cancel(id string) bool {
 	cancel, ok := s.state.Load(id)
 	if !ok {
 		return false
 	}
 
-	cancel.(context.CancelFunc)()
+	cancel.(context.CancelCauseFunc)(nil)
 
 	return true
 }
 
-func (s *State) IsEmpty() bool {
-	return s.len.Load() == 0
+// CancelAll cancels the jobs in progress with the given cause and returns their number.
+func (s *State) CancelAll(cause error) int {
+	var n int
+	s.state.Range(func(_, cancel any) bool {
+		cancel.(context.CancelCauseFunc)(cause)
+		n++
+
+		return true
+	})
+
+	return n
+}
+
+// Close refuses to start new jobs, so Wait returns once the jobs in progress are finished.
+func (s *State) Close() {
+	s.closing.Lock()
+	defer s.closing.Unlock()
+
+	s.closed = true
+}
+
+// Wait blocks until all the jobs are finished or ctx is done.
+func (s *State) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of jobs in progress.
+func (s *State) Len() int {
+	return int(s.len.Load())
+}
+
+func (s *State) nextID() (string, error) {
+	if s.randomIDs {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate random ID: %w", err)
+		}
+
+		return hex.EncodeToString(b), nil
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	id := s.uid + 1
+	if err := s.saveLastID(id); err != nil {
+		return "", err
+	}
+	s.uid = id
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+func (s *State) loadLastID() (uint64, error) {
+	name := filepath.Join(s.storePath, lastIDFile)
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to read %q: %w", name, err)
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %w", name, err)
+	}
+
+	return id, nil
+}
+
+// saveLastID replaces the high-water mark file atomically.
+// The file and the directory are synced, so the mark does not go back after a power loss.
+func (s *State) saveLastID(id uint64) error {
+	name := filepath.Join(s.storePath, lastIDFile)
+	tmpName := fmt.Sprintf("%s.tmp", name)
+
+	f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", tmpName, err)
+	}
+
+	if _, err := f.Write([]byte(strconv.FormatUint(id, 10))); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("failed to write %q: %w", tmpName, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("failed to sync %q: %w", tmpName, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %q: %w", tmpName, err)
+	}
+
+	if err := os.Rename(tmpName, name); err != nil {
+		return fmt.Errorf("failed to rename %q to %q: %w", tmpName, name, err)
+	}
+
+	dir, err := os.Open(s.storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", s.storePath, err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %q: %w", s.storePath, err)
+	}
+
+	return nil
 }
@@ -2,23 +2,36 @@ package progress
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"strconv"
 	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
 )
 
 func TestState_Cancel(t *testing.T) {
-	s := new(State)
-	id, ctx := s.Start()
+	storePath := "./store/"
+	s, err := New(storePath, false)
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+	id, ctx, err := s.Start(context.Background())
+	if err != nil {
+		t.Errorf("Start() error = %v", err)
+		return
+	}
 	tests := []struct {
 		name string
-		id   uint64
+		id   string
 		want bool
 	}{
 		{
 			name: "should return false when ID is not found",
-			id:   0,
+			id:   "0",
 			want: false,
 		},
 		{
@@ -40,15 +53,20 @@ func TestState_Cancel(t *testing.T) {
 			}
 		})
 	}
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
 }
 
 func TestNew(t *testing.T) {
-	id1 := rand.Uint64()
-	id2 := rand.Uint64()
+	id1 := rand.Uint64() >> 1
+	id2 := rand.Uint64() >> 1
 	storePath := "./store/"
 	tests := []struct {
 		name      string
 		storePath string
+		files     []uint64
+		restart   bool
 		wantID    uint64
 		wantErr   bool
 	}{
@@ -61,9 +79,17 @@ func TestNew(t *testing.T) {
 		{
 			name:      "should start from max ID + 1 when the store directory is not empty",
 			storePath: storePath,
+			files:     []uint64{id1, id2},
 			wantID:    max(id1, id2) + 1,
 			wantErr:   false,
 		},
+		{
+			name:      "should not reuse IDs after the stored results are removed",
+			storePath: storePath,
+			restart:   true,
+			wantID:    2,
+			wantErr:   false,
+		},
 	}
 	for _, tt := range tests {
 		//set up
@@ -73,24 +99,36 @@ func TestNew(t *testing.T) {
 		if err := os.Mkdir(storePath, 0777); err != nil {
 			t.Errorf("mkdir %q failed: %s", storePath, err)
 		}
-		if tt.wantID > 1 {
-			for _, id := range []uint64{id1, id2} {
-				name := fmt.Sprintf("%s%d.json", storePath, id)
-				_, err := os.Create(name)
-				if err != nil {
-					t.Errorf("failed to create %q: %s", name, err)
-				}
+		for _, id := range tt.files {
+			name := fmt.Sprintf("%s%d.json", storePath, id)
+			_, err := os.Create(name)
+			if err != nil {
+				t.Errorf("failed to create %q: %s", name, err)
 			}
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
-			state, err := New(tt.storePath)
+			state, err := New(tt.storePath, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			id, _ := state.Start()
-			if id != tt.wantID {
+			if tt.restart {
+				if _, _, err := state.Start(context.Background()); err != nil {
+					t.Errorf("Start() error = %v", err)
+					return
+				}
+				if state, err = New(tt.storePath, false); err != nil {
+					t.Errorf("New() error = %v", err)
+					return
+				}
+			}
+			id, _, err := state.Start(context.Background())
+			if err != nil {
+				t.Errorf("Start() error = %v", err)
+				return
+			}
+			if id != strconv.FormatUint(tt.wantID, 10) {
 				t.Errorf("Start() got = %v, want %v", id, tt.wantID)
 			}
 		})
@@ -99,3 +137,90 @@ func TestNew(t *testing.T) {
 		t.Errorf("rm %q failed: %s", storePath, err)
 	}
 }
+
+func TestState_Start(t *testing.T) {
+	storePath := "./store/"
+	state, err := New(storePath, true)
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	t.Run("should generate random IDs", func(t *testing.T) {
+		id1, _, err := state.Start(context.Background())
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+			return
+		}
+		id2, _, err := state.Start(context.Background())
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+			return
+		}
+		if id1 == id2 {
+			t.Errorf("Expected different IDs - got %q twice", id1)
+		}
+		for _, id := range []string{id1, id2} {
+			if parsed, err := models.ParseID(id); err != nil || parsed != id || len(id) != models.RandomIDLength {
+				t.Errorf("Invalid random ID %q", id)
+			}
+		}
+	})
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestState_Wait(t *testing.T) {
+	storePath := "./store/"
+	state, err := New(storePath, false)
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	t.Run("should return when there are no jobs", func(t *testing.T) {
+		if err := state.Wait(context.Background()); err != nil {
+			t.Errorf("Wait() error = %v", err)
+		}
+	})
+
+	id, ctx, err := state.Start(context.Background())
+	if err != nil {
+		t.Errorf("Start() error = %v", err)
+		return
+	}
+
+	t.Run("should fail when the deadline is exceeded", func(t *testing.T) {
+		c, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := state.Wait(c); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("should cancel jobs with the cause and wait until they are finished", func(t *testing.T) {
+		if n := state.CancelAll(models.ErrInterrupted); n != 1 {
+			t.Errorf("CancelAll() = %d, want 1", n)
+		}
+		if !errors.Is(context.Cause(ctx), models.ErrInterrupted) {
+			t.Errorf("job context cause = %v, want %v", context.Cause(ctx), models.ErrInterrupted)
+		}
+		go state.Finish(id)
+		if err := state.Wait(context.Background()); err != nil {
+			t.Errorf("Wait() error = %v", err)
+		}
+	})
+
+	t.Run("should not start jobs after close", func(t *testing.T) {
+		state.Close()
+		if _, _, err := state.Start(context.Background()); !errors.Is(err, models.ErrShuttingDown) {
+			t.Errorf("Start() error = %v, want %v", err, models.ErrShuttingDown)
+		}
+	})
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+// Limiter is a token bucket rate limiter keyed by client.
+// Buckets are kept in LRU order: full (idle) buckets and the least recently used ones
+// over maxBuckets are evicted, so memory usage is bounded.
+type Limiter struct {
+	rate       float64
+	burst      uint32
+	maxBuckets int
+	now        func() time.Time
+	m          sync.Mutex
+	buckets    map[string]*list.Element
+	lru        *list.List
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func New(rate float64, burst uint32, maxBuckets int) *Limiter {
+	return &Limiter{
+		rate:       rate,
+		burst:      burst,
+		maxBuckets: maxBuckets,
+		now:        time.Now,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (l *Limiter) Allow(key string) models.RateLimit {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := l.now()
+	l.evict(now)
+
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		b = e.Value.(*bucket)
+		b.tokens = l.refill(b, now)
+		b.last = now
+		l.lru.MoveToFront(e)
+	} else {
+		b = &bucket{
+			key:    key,
+			tokens: float64(l.burst),
+			last:   now,
+		}
+		l.buckets[key] = l.lru.PushFront(b)
+		if l.lru.Len() > l.maxBuckets {
+			l.remove(l.lru.Back())
+		}
+	}
+
+	result := models.RateLimit{
+		Limit: l.burst,
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = uint32(math.Floor(b.tokens))
+	result.Reset = l.duration(float64(l.burst) - b.tokens)
+
+	return result
+}
+
+// Len returns the number of tracked buckets.
+func (l *Limiter) Len() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.lru.Len()
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// evict removes the least recently used buckets which are full again,
+// they are indistinguishable from new ones.
+func (l *Limiter) evict(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		if l.refill(e.Value.(*bucket), now) < float64(l.burst) {
+			return
+		}
+
+		l.remove(e)
+	}
+}
+
+func (l *Limiter) remove(e *list.Element) {
+	l.lru.Remove(e)
+	delete(l.buckets, e.Value.(*bucket).key)
+}
+
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New(2, 3, 2)
+	l.now = func() time.Time {
+		return now
+	}
+	tests := []struct {
+		name          string
+		key           string
+		elapsed       time.Duration
+		wantAllowed   bool
+		wantRemaining uint32
+	}{
+		{
+			name:          "should allow a burst",
+			key:           "a",
+			wantAllowed:   true,
+			wantRemaining: 2,
+		},
+		{
+			name:          "should allow a burst",
+			key:           "a",
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+		{
+			name:          "should allow a burst",
+			key:           "a",
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+		{
+			name:          "should reject requests over the burst",
+			key:           "a",
+			wantAllowed:   false,
+			wantRemaining: 0,
+		},
+		{
+			name:          "should not limit other clients",
+			key:           "b",
+			wantAllowed:   true,
+			wantRemaining: 2,
+		},
+		{
+			name:          "should refill tokens with time",
+			key:           "a",
+			elapsed:       500 * time.Millisecond,
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			got := l.Allow(tt.key)
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("Allow() allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if got.Remaining != tt.wantRemaining {
+				t.Errorf("Allow() remaining = %v, want %v", got.Remaining, tt.wantRemaining)
+			}
+			if !got.Allowed && got.RetryAfter <= 0 {
+				t.Errorf("Allow() retry after = %v, want positive", got.RetryAfter)
+			}
+		})
+	}
+
+	t.Run("should evict buckets over the limit", func(t *testing.T) {
+		l.Allow("c")
+		if l.Len() > 2 {
+			t.Errorf("Len() = %d, want no more than %d", l.Len(), 2)
+		}
+	})
+
+	t.Run("should evict idle buckets", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		l.Allow("d")
+		if l.Len() != 1 {
+			t.Errorf("Len() = %d, want %d", l.Len(), 1)
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/logctx"
+)
+
+// Scheduler creates the jobs of the schedules by calling the POST links handler,
+// so the jobs pass the same limits as the ones created by clients.
+type Scheduler struct {
+	logger    *slog.Logger
+	linksPath string
+	createJob contracts.Handler
+	state     contracts.State
+	store     contracts.Store
+	// keys is nil when authentication is off
+	keys contracts.APIKeys
+	now  func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	m         sync.Mutex
+	schedules map[string]*entry
+}
+
+type entry struct {
+	schedule models.Schedule
+	cancel   context.CancelFunc
+}
+
+// New resumes the stored schedules.
+func New(
+	logger *slog.Logger,
+	linksPath string,
+	createJob contracts.Handler,
+	state contracts.State,
+	store contracts.Store,
+	keys contracts.APIKeys,
+) (*Scheduler, error) {
+	stored, err := store.ListSchedules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		logger:    logger,
+		linksPath: linksPath,
+		createJob: createJob,
+		state:     state,
+		store:     store,
+		keys:      keys,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+		schedules: make(map[string]*entry, len(stored)),
+	}
+
+	for _, schedule := range stored {
+		s.start(schedule)
+	}
+
+	return s, nil
+}
+
+// Create assigns an ID to the schedule, stores it and starts running it.
+func (s *Scheduler) Create(schedule models.Schedule) (models.Schedule, error) {
+	b := make([]byte, models.RandomIDLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return schedule, fmt.Errorf("failed to generate schedule ID: %w", err)
+	}
+
+	schedule.ID = hex.EncodeToString(b)
+	schedule.CreatedAt = s.now()
+	schedule.LastJobID, schedule.LastRunAt, schedule.NextRunAt = "", nil, nil
+	if err := s.store.SaveSchedule(schedule); err != nil {
+		return schedule, fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	s.start(schedule)
+
+	return s.withNextRun(schedule), nil
+}
+
+// List returns all the schedules ordered by creation time.
+func (s *Scheduler) List() []models.Schedule {
+	s.m.Lock()
+	result := make([]models.Schedule, 0, len(s.schedules))
+	for _, e := range s.schedules {
+		result = append(result, e.schedule)
+	}
+	s.m.Unlock()
+
+	for i := range result {
+		result[i] = s.withNextRun(result[i])
+	}
+	slices.SortFunc(result, func(a, b models.Schedule) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return result
+}
+
+func (s *Scheduler) Get(id string) (models.Schedule, bool) {
+	s.m.Lock()
+	e, ok := s.schedules[id]
+	s.m.Unlock()
+
+	if !ok {
+		return models.Schedule{}, false
+	}
+
+	return s.withNextRun(e.schedule), true
+}
+
+// Delete stops the schedule and removes it from the store. The jobs already created are kept.
+func (s *Scheduler) Delete(id string) (bool, error) {
+	s.m.Lock()
+	e, ok := s.schedules[id]
+	if ok {
+		e.cancel()
+		delete(s.schedules, id)
+	}
+	s.m.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if _, err := s.store.RemoveSchedule(id); err != nil {
+		return true, fmt.Errorf("failed to remove schedule %s: %w", id, err)
+	}
+
+	return true, nil
+}
+
+// Shutdown stops creating jobs and waits for the runs in progress to submit their jobs.
+func (s *Scheduler) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) start(schedule models.Schedule) {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	s.m.Lock()
+	s.schedules[schedule.ID] = &entry{schedule: schedule, cancel: cancel}
+	s.m.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx, schedule.ID)
+}
+
+func (s *Scheduler) run(ctx context.Context, id string) {
+	defer s.wg.Done()
+
+	logger := s.logger.With(slog.String("schedule_id", id))
+	for {
+		schedule, ok := s.get(id)
+		if !ok {
+			return
+		}
+
+		next, err := schedule.Next(s.now())
+		if err != nil {
+			logger.Error("schedule stopped", slog.Any("error", err))
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.trigger(ctx, logger, schedule)
+	}
+}
+
+// trigger creates a job of the schedule unless the previous one is still in progress.
+func (s *Scheduler) trigger(ctx context.Context, logger *slog.Logger, schedule models.Schedule) {
+	if len(schedule.LastJobID) != 0 && s.state.Check(schedule.LastJobID) {
+		logger.Info("schedule run skipped, previous job is in progress", slog.String("job_id", schedule.LastJobID))
+		return
+	}
+
+	body, err := json.Marshal(schedule.Request)
+	if err != nil {
+		logger.Error("failed to JSON encode schedule request", slog.Any("error", err))
+		return
+	}
+
+	target := s.linksPath
+	if len(schedule.Options) != 0 {
+		target += "?" + schedule.Options
+	}
+
+	ctx = logctx.With(ctx, slog.String("schedule_id", schedule.ID))
+	ctx = context.WithValue(ctx, contracts.ContextKey("scheduleID"), schedule.ID)
+	if schedule.Compare && len(schedule.LastJobID) != 0 {
+		ctx = context.WithValue(ctx, contracts.ContextKey("compareTo"), schedule.LastJobID)
+	}
+	if len(schedule.KeyName) != 0 && s.keys != nil {
+		apiKey, ok := s.keys.Named(schedule.KeyName)
+		if !ok {
+			logger.Warn("schedule run skipped, API key is not found", slog.String("key_name", schedule.KeyName))
+			return
+		}
+		ctx = context.WithValue(ctx, contracts.ContextKey("apiKey"), apiKey)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		logger.Error("failed to create schedule request", slog.Any("error", err))
+		return
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	w := newRecorder()
+	s.createJob(w, r)
+	if w.status != http.StatusAccepted {
+		logger.Warn(
+			"schedule run failed",
+			slog.Int("status", w.status),
+			slog.String("response", strings.TrimSpace(w.body.String())),
+		)
+		return
+	}
+
+	// the schedule token gives access to all the jobs of the schedule
+	jobID := w.body.String()
+	if err := s.store.UpdateJob(jobID, func(job *models.Job) {
+		job.ScheduleID = schedule.ID
+		job.ReplayTokenHashes = append(job.ReplayTokenHashes, schedule.TokenHash)
+	}); err != nil {
+		logger.Error("failed to link job to schedule", slog.String("job_id", jobID), slog.Any("error", err))
+	}
+
+	now := s.now()
+	s.m.Lock()
+	e, ok := s.schedules[schedule.ID]
+	if ok {
+		e.schedule.LastJobID = jobID
+		e.schedule.LastRunAt = &now
+		schedule = e.schedule
+	}
+	s.m.Unlock()
+
+	// a schedule deleted during the run is not saved again
+	if !ok {
+		return
+	}
+
+	if err := s.store.SaveSchedule(schedule); err != nil {
+		logger.Error("failed to save schedule", slog.Any("error", err))
+	}
+	logger.Info("schedule job created", slog.String("job_id", jobID))
+}
+
+func (s *Scheduler) get(id string) (models.Schedule, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	e, ok := s.schedules[id]
+	if !ok {
+		return models.Schedule{}, false
+	}
+
+	return e.schedule, true
+}
+
+func (s *Scheduler) withNextRun(schedule models.Schedule) models.Schedule {
+	if next, err := schedule.Next(s.now()); err == nil {
+		schedule.NextRunAt = &next
+	}
+
+	return schedule
+}
+
+// recorder keeps the response of the POST links handler.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		header: make(http.Header),
+	}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/internal/services/store"
+)
+
+type fakeState struct {
+	inProgress bool
+}
+
+func (s *fakeState) Start(_ context.Context) (string, context.Context, error) {
+	return "", nil, nil
+}
+
+func (s *fakeState) Finish(string) {}
+
+func (s *fakeState) Check(string) bool {
+	return s.inProgress
+}
+
+func (s *fakeState) Cancel(string) bool {
+	return false
+}
+
+func TestScheduler(t *testing.T) {
+	storePath := "./store/"
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tests := []struct {
+		name       string
+		inProgress bool
+		restart    bool
+		want       int64
+	}{
+		{
+			name: "should create jobs on schedule",
+			want: 2,
+		},
+		{
+			name:       "should skip runs while the previous job is in progress",
+			inProgress: true,
+			want:       1,
+		},
+		{
+			name:    "should resume stored schedules",
+			restart: true,
+			want:    2,
+		},
+	}
+	for _, tt := range tests {
+		//set up
+		if err := os.Mkdir(storePath, 0777); err != nil {
+			t.Errorf("mkdir %q failed: %s", storePath, err)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			jobsStore := store.New(logger, storePath)
+			var created atomic.Int64
+			createJob := func(w http.ResponseWriter, r *http.Request) {
+				id := fmt.Sprintf("%d", created.Add(1))
+				if err := jobsStore.SaveJob(models.Job{ID: id}); err != nil {
+					t.Errorf("SaveJob() error = %v", err)
+				}
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = fmt.Fprint(w, id)
+			}
+
+			s, err := New(logger, "/links", createJob, &fakeState{inProgress: tt.inProgress}, jobsStore, nil)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			schedule := models.Schedule{
+				Request:   models.Request{Links: []models.Link{{URL: "https://example.com"}}},
+				Interval:  "1s",
+				TokenHash: "hash",
+			}
+			if schedule, err = s.Create(schedule); err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+
+			if tt.restart {
+				s.Shutdown()
+				if s, err = New(logger, "/links", createJob, &fakeState{}, jobsStore, nil); err != nil {
+					t.Errorf("New() error = %v", err)
+					return
+				}
+			}
+
+			time.Sleep(2500 * time.Millisecond)
+			s.Shutdown()
+
+			if got := created.Load(); got != tt.want {
+				t.Errorf("created jobs = %d, want %d", got, tt.want)
+			}
+
+			job, err := jobsStore.LoadJob("1")
+			if err != nil {
+				t.Errorf("LoadJob() error = %v", err)
+				return
+			}
+			if job.ScheduleID != schedule.ID || len(job.ReplayTokenHashes) != 1 || job.ReplayTokenHashes[0] != "hash" {
+				t.Errorf("job is not linked to schedule: %+v", job)
+			}
+
+			if got, ok := s.Get(schedule.ID); !ok || len(got.LastJobID) == 0 {
+				t.Errorf("Get() = %+v, %v, want the last job", got, ok)
+			}
+		})
+
+		if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+			t.Errorf("rm %q failed: %s", storePath, err)
+		}
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"yegorov-boris/affise-test-task/internal/contracts"
 	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/tracing"
 )
 
 type Scraper struct {
 	logger              *slog.Logger
-	maxParallelOutPerIn uint32
+	maxParallelOutPerIn atomic.Uint32
 	httpClient          contracts.HTTPClient
 }
 
@@ -20,24 +22,48 @@ func New(
 	maxParallelOutPerIn uint32,
 	httpClient contracts.HTTPClient,
 ) *Scraper {
-	return &Scraper{
-		logger:              logger,
-		maxParallelOutPerIn: maxParallelOutPerIn,
-		httpClient:          httpClient,
+	s := &Scraper{
+		logger:     logger,
+		httpClient: httpClient,
 	}
+	s.SetMaxParallelOutPerIn(maxParallelOutPerIn)
+
+	return s
+}
+
+// SetMaxParallelOutPerIn changes the limit for the jobs started after the call.
+func (s *Scraper) SetMaxParallelOutPerIn(maxParallelOutPerIn uint32) {
+	s.maxParallelOutPerIn.Store(maxParallelOutPerIn)
 }
 
-func (s *Scraper) Scrap(ctx context.Context, input models.Input) ([]models.Output, string) {
+// Scrap gets every link concurrently, the job fails fast on the first failed request or a check error,
+// which is returned as the error message. The check is optional.
+func (s *Scraper) Scrap(ctx context.Context, input models.Input, check models.Check) ([]models.Output, string) {
 	var wg sync.WaitGroup
 
-	s.logger.Info(fmt.Sprintf("started scrapping %v", input))
+	ctx, span := tracing.Start(ctx, "scrape", tracing.KindInternal)
+	span.SetAttribute("links.count", len(input))
+	defer span.End()
+
+	s.logger.InfoContext(ctx, "started scrapping", slog.Any("links", input))
 	linksCount := len(input)
 	results := make([]models.Output, linksCount)
-	bucket := make(chan struct{}, s.maxParallelOutPerIn)
+	bucket := make(chan struct{}, s.maxParallelOutPerIn.Load())
 	c, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	errMsgs := make([]string, linksCount)
+	// the first failure is the result of the job, the requests canceled because of it fail silently
+	var (
+		failOnce sync.Once
+		errMsg   string
+	)
+	fail := func(msg string) {
+		failOnce.Do(func() {
+			errMsg = msg
+			cancel()
+		})
+	}
+
 	wg.Add(linksCount)
 	for i, link := range input {
 		go func(i int, link string) {
@@ -49,38 +75,61 @@ func (s *Scraper) Scrap(ctx context.Context, input models.Input) ([]models.Outpu
 					<-bucket
 				}()
 
-				output, err := s.httpClient.Get(c, link)
+				output, err := s.httpClient.Get(c, link, nil)
 				if err != nil {
-					cancel()
-					s.logger.Error(fmt.Sprintf("failed to get response for link %q: %s", link, err))
-					errMsgs[i] = fmt.Sprintf("Request to %s failed.", link)
+					if ctx.Err() != nil {
+						fail(s.canceled(ctx, link))
+
+						return
+					}
+					if c.Err() == nil {
+						s.logger.ErrorContext(ctx, "failed to get response", slog.String("link", link), slog.Any("error", err))
+					}
+					fail(fmt.Sprintf("Request to %s failed.", link))
 
 					return
 				}
 
+				if check != nil {
+					if err := check(i, output); err != nil {
+						s.logger.InfoContext(ctx, "assertions failed", slog.String("link", link), slog.Any("error", err))
+						fail(fmt.Sprintf("Assertions for %s failed: %s.", link, err))
+
+						return
+					}
+				}
+
 				results[i] = output
 			case <-ctx.Done():
-				switch ctx.Err() {
-				case context.Canceled:
-					s.logger.Info("scrapping canceled by client")
-					errMsgs[i] = "Requests canceled by client."
-				case context.DeadlineExceeded:
-					s.logger.Info(fmt.Sprintf("%s deadline exceeded", link))
-					errMsgs[i] = "Request to %s timeout exceeded."
-				default:
-					s.logger.Info(fmt.Sprintf("request to %s failed", link))
-					errMsgs[i] = fmt.Sprintf("Request to %s failed.", link)
-				}
+				fail(s.canceled(ctx, link))
 			}
 		}(i, link)
 	}
 	wg.Wait()
 
-	for _, msg := range errMsgs {
-		if msg != "" {
-			return nil, msg
-		}
+	if len(errMsg) != 0 {
+		span.SetStatus(tracing.StatusError, errMsg)
+
+		return nil, errMsg
 	}
 
 	return results, ""
 }
+
+// canceled returns the error message of a job whose context is done.
+func (s *Scraper) canceled(ctx context.Context, link string) string {
+	switch ctx.Err() {
+	case context.Canceled:
+		s.logger.InfoContext(ctx, "scrapping canceled by client")
+
+		return "Requests canceled by client."
+	case context.DeadlineExceeded:
+		s.logger.InfoContext(ctx, "deadline exceeded", slog.String("link", link))
+
+		return fmt.Sprintf("Request to %s timeout exceeded.", link)
+	default:
+		s.logger.InfoContext(ctx, "request failed", slog.String("link", link))
+
+		return fmt.Sprintf("Request to %s failed.", link)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/http"
 	"reflect"
 	"sync"
 	"testing"
@@ -14,6 +15,7 @@ import (
 type (
 	httpClientMock struct {
 		expected      map[string]httpClientMockResponse
+		cancelable    bool // requests fail when the context is done
 		maxParallel   uint32
 		parallelCount uint32
 		m             sync.Mutex
@@ -25,7 +27,7 @@ type (
 	}
 )
 
-func (c *httpClientMock) Get(ctx context.Context, link string) (models.Output, error) {
+func (c *httpClientMock) Get(ctx context.Context, link string, header http.Header) (models.Output, error) {
 	c.m.Lock()
 	c.parallelCount++
 	if c.parallelCount > c.maxParallel {
@@ -44,14 +46,23 @@ func (c *httpClientMock) Get(ctx context.Context, link string) (models.Output, e
 	if !ok {
 		return models.Output{}, errors.New("unexpected link")
 	}
+	delay := 100 * time.Millisecond
 	if response.err != nil {
-		time.Sleep(50 * time.Millisecond)
+		delay = 50 * time.Millisecond
+	}
+
+	if !c.cancelable {
+		time.Sleep(delay)
 
 		return response.output, response.err
 	}
-	time.Sleep(100 * time.Millisecond)
 
-	return response.output, response.err
+	select {
+	case <-time.After(delay):
+		return response.output, response.err
+	case <-ctx.Done():
+		return models.Output{}, ctx.Err()
+	}
 }
 
 func TestScraper_Scrap(t *testing.T) {
@@ -103,12 +114,14 @@ func TestScraper_Scrap(t *testing.T) {
 	type args struct {
 		ctx   context.Context
 		input models.Input
+		check models.Check
 	}
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   []models.Output
+		name       string
+		fields     fields
+		args       args
+		want       []models.Output
+		wantErrMsg string
 	}{
 		{
 			name: "should get request for every link and throttle outgoing requests",
@@ -127,13 +140,48 @@ func TestScraper_Scrap(t *testing.T) {
 				outputs["https://example3.com"].output,
 			},
 		},
+		{
+			name: "should fail fast when the check fails",
+			fields: fields{
+				logger:              logger,
+				maxParallelOutPerIn: maxParallelOutPerIn,
+				httpClient:          clientMock(),
+			},
+			args: args{
+				ctx:   context.Background(),
+				input: input[1:2],
+				check: func(i int, output models.Output) error {
+					return errors.New("status 200 is not expected")
+				},
+			},
+			want:       nil,
+			wantErrMsg: "Assertions for https://example2.com failed: status 200 is not expected.",
+		},
+		{
+			name: "should return the first failure instead of the requests canceled by it",
+			fields: fields{
+				logger:              logger,
+				maxParallelOutPerIn: maxParallelOutPerIn,
+				httpClient:          &httpClientMock{expected: outputs, cancelable: true},
+			},
+			args: args{
+				ctx:   context.Background(),
+				input: []string{"https://example1.com", "https://unexpected.com"},
+			},
+			want:       nil,
+			wantErrMsg: "Request to https://unexpected.com failed.",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := New(tt.fields.logger, tt.fields.maxParallelOutPerIn, tt.fields.httpClient)
-			if got, _ := s.Scrap(tt.args.ctx, tt.args.input); !reflect.DeepEqual(got, tt.want) {
+			got, errMsg := s.Scrap(tt.args.ctx, tt.args.input, tt.args.check)
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Scrap() = %v, want %v", got, tt.want)
 			}
+			if errMsg != tt.wantErrMsg {
+				t.Errorf("Scrap() errMsg = %q, want %q", errMsg, tt.wantErrMsg)
+			}
 			if tt.fields.httpClient.maxParallel > tt.fields.maxParallelOutPerIn {
 				t.Errorf(
 					"Throttling failed: expected no more than %d concurrent outgoing requests - get %d",
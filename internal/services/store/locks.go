@@ -0,0 +1,65 @@
+package store
+
+import "sync"
+
+// locks holds a reader/writer lock per stored result,
+// so files are never removed or rewritten while being read.
+type locks struct {
+	m     sync.Mutex
+	locks map[string]*lock
+}
+
+type lock struct {
+	sync.RWMutex
+	refs int
+}
+
+func newLocks() *locks {
+	return &locks{
+		locks: make(map[string]*lock),
+	}
+}
+
+func (l *locks) rLock(id string) func() {
+	lk := l.acquire(id)
+	lk.RLock()
+
+	return sync.OnceFunc(func() {
+		lk.RUnlock()
+		l.release(id, lk)
+	})
+}
+
+func (l *locks) lock(id string) func() {
+	lk := l.acquire(id)
+	lk.Lock()
+
+	return sync.OnceFunc(func() {
+		lk.Unlock()
+		l.release(id, lk)
+	})
+}
+
+func (l *locks) acquire(id string) *lock {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	lk, ok := l.locks[id]
+	if !ok {
+		lk = new(lock)
+		l.locks[id] = lk
+	}
+	lk.refs++
+
+	return lk
+}
+
+func (l *locks) release(id string, lk *lock) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	lk.refs--
+	if lk.refs == 0 {
+		delete(l.locks, id)
+	}
+}
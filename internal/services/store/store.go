@@ -1,31 +1,55 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
+	"yegorov-boris/affise-test-task/internal/contracts"
 	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/tracing"
 )
 
 type Store struct {
 	logger *slog.Logger
 	path   string
+	locks  *locks
+}
+
+// scheduleRecord stores the token hash, which is hidden from the API responses.
+type scheduleRecord struct {
+	models.Schedule
+	TokenHash string `json:"token_hash"`
+}
+
+type file struct {
+	*os.File
+	unlock func()
 }
 
 func New(logger *slog.Logger, path string) *Store {
 	return &Store{
 		logger: logger,
 		path:   path,
+		locks:  newLocks(),
 	}
 }
 
-func (s *Store) Save(id uint64, body []models.Output, bodyStr string) {
+func (s *Store) Save(ctx context.Context, id string, body []models.Output, bodyStr string) {
 	if len(body) == 0 && len(bodyStr) == 0 {
 		return
 	}
 
+	ctx, span := tracing.Start(ctx, "store.save", tracing.KindInternal)
+	defer span.End()
+
 	var (
 		b   []byte
 		err error
@@ -36,13 +60,450 @@ func (s *Store) Save(id uint64, body []models.Output, bodyStr string) {
 	} else {
 		b, err = json.Marshal(body)
 		if err != nil {
-			s.logger.Error(fmt.Sprintf("failed to JSON encode results: %s", err))
+			span.SetError(err)
+			s.logger.ErrorContext(ctx, "failed to JSON encode results", slog.Any("error", err))
 			return
 		}
 	}
 
-	name := filepath.Join(s.path, fmt.Sprintf("%d.json", id))
-	if err := os.WriteFile(name, b, 0644); err != nil {
-		s.logger.Error(fmt.Sprintf("failed to write results to disk: %s", err))
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	if err := s.write(s.name(id), b); err != nil {
+		span.SetError(err)
+		s.logger.ErrorContext(ctx, "failed to write results to disk", slog.Any("error", err))
+		return
+	}
+
+	hash := models.ContentHash(string(b))
+	setHash := func(job *models.Job) {
+		job.ResultHash = hash
+	}
+	if err := s.updateJob(id, setHash); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		span.SetError(err)
+		s.logger.ErrorContext(ctx, "failed to save result hash", slog.Any("error", err))
+	}
+}
+
+// Open returns the stored result by ID.
+// The result can not be removed or rewritten until the returned reader is closed.
+func (s *Store) Open(id string) (contracts.File, error) {
+	unlock := s.locks.rLock(id)
+
+	f, err := os.Open(s.name(id))
+	if err != nil {
+		unlock()
+
+		return nil, fmt.Errorf("failed to open file %q: %w", s.name(id), err)
+	}
+
+	return &file{
+		File:   f,
+		unlock: unlock,
+	}, nil
+}
+
+// Remove deletes the stored result and the job record by ID waiting for the active reads to finish.
+// It returns false when neither of them is found.
+func (s *Store) Remove(id string) (bool, error) {
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	var job models.Job
+	if b, err := os.ReadFile(s.jobName(id)); err == nil {
+		_ = json.Unmarshal(b, &job)
+	}
+
+	removed := false
+	for _, name := range []string{s.name(id), s.jobName(id)} {
+		if err := os.Remove(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return removed, fmt.Errorf("failed to remove file %q: %w", name, err)
+		}
+
+		removed = true
+	}
+
+	if len(job.IdempotencyKeyHash) != 0 {
+		if err := s.removeIdempotencyKey(job.IdempotencyKeyHash, id); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+func (s *Store) SaveJob(job models.Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to JSON encode job: %w", err)
+	}
+
+	unlock := s.locks.lock(job.ID)
+	defer unlock()
+
+	return s.write(s.jobName(job.ID), b)
+}
+
+func (s *Store) LoadJob(id string) (models.Job, error) {
+	var job models.Job
+
+	unlock := s.locks.rLock(id)
+	defer unlock()
+
+	b, err := os.ReadFile(s.jobName(id))
+	if err != nil {
+		return job, fmt.Errorf("failed to read job %q: %w", id, err)
+	}
+
+	if err := json.Unmarshal(b, &job); err != nil {
+		return job, fmt.Errorf("failed to decode job %q from JSON: %w", id, err)
+	}
+
+	return job, nil
+}
+
+// UpdateJob changes the job record, concurrent updates are applied one by one.
+func (s *Store) UpdateJob(id string, update func(*models.Job)) error {
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	return s.updateJob(id, update)
+}
+
+// updateJob changes the job record, the caller holds the lock of the ID.
+func (s *Store) updateJob(id string, update func(*models.Job)) error {
+	b, err := os.ReadFile(s.jobName(id))
+	if err != nil {
+		return fmt.Errorf("failed to read job %q: %w", id, err)
+	}
+
+	var job models.Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		return fmt.Errorf("failed to decode job %q from JSON: %w", id, err)
+	}
+
+	update(&job)
+	if b, err = json.Marshal(job); err != nil {
+		return fmt.Errorf("failed to JSON encode job: %w", err)
+	}
+
+	return s.write(s.jobName(id), b)
+}
+
+// SaveIdempotencyKey stores the key unless it is already stored after replaceBefore.
+// It returns an error wrapping fs.ErrExist in that case.
+func (s *Store) SaveIdempotencyKey(key models.IdempotencyKey, replaceBefore time.Time) error {
+	unlock := s.locks.lock(idempotencyLockID(key.Hash))
+	defer unlock()
+
+	stored, err := s.readIdempotencyKey(key.Hash)
+	if err == nil && !stored.CreatedAt.Before(replaceBefore) {
+		return fmt.Errorf("idempotency key is used by job %s: %w", stored.JobID, fs.ErrExist)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to JSON encode idempotency key: %w", err)
+	}
+
+	return s.write(s.idempotencyKeyName(key.Hash), b)
+}
+
+func (s *Store) LoadIdempotencyKey(hash string) (models.IdempotencyKey, error) {
+	unlock := s.locks.rLock(idempotencyLockID(hash))
+	defer unlock()
+
+	return s.readIdempotencyKey(hash)
+}
+
+// SaveSchedule writes the schedule along with its token hash to the schedules directory.
+func (s *Store) SaveSchedule(schedule models.Schedule) error {
+	b, err := json.Marshal(scheduleRecord{Schedule: schedule, TokenHash: schedule.TokenHash})
+	if err != nil {
+		return fmt.Errorf("failed to JSON encode schedule: %w", err)
+	}
+
+	if err := os.MkdirAll(s.schedulesPath(), 0755); err != nil {
+		return fmt.Errorf("failed to create schedules directory: %w", err)
+	}
+
+	unlock := s.locks.lock(scheduleLockID(schedule.ID))
+	defer unlock()
+
+	return s.write(s.scheduleName(schedule.ID), b)
+}
+
+// ListSchedules reads all the stored schedules, a missing schedules directory means there are none.
+func (s *Store) ListSchedules() ([]models.Schedule, error) {
+	entries, err := os.ReadDir(s.schedulesPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to list %q: %w", s.schedulesPath(), err)
+	}
+
+	result := make([]models.Schedule, 0, len(entries))
+	for _, e := range entries {
+		id, found := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !found {
+			continue
+		}
+
+		record, err := s.readSchedule(id)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		result = append(result, record)
 	}
+
+	return result, nil
+}
+
+// RemoveSchedule deletes the schedule by ID, it returns false when the schedule is not found.
+func (s *Store) RemoveSchedule(id string) (bool, error) {
+	unlock := s.locks.lock(scheduleLockID(id))
+	defer unlock()
+
+	name := s.scheduleName(id)
+	if err := os.Remove(name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to remove file %q: %w", name, err)
+	}
+
+	return true, nil
+}
+
+// Purge deletes all the stored results along with their job records and returns their count.
+// Records of the jobs still in progress are kept.
+func (s *Store) Purge() (int, error) {
+	entries, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, e := range entries {
+		if !e.HasResult {
+			continue
+		}
+
+		removed, err := s.Remove(e.ID)
+		if err != nil {
+			return count, err
+		}
+
+		if removed {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// List returns the stored results and job records grouped by ID.
+func (s *Store) List() ([]models.StoreEntry, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %q: %w", s.path, err)
+	}
+
+	result := make([]models.StoreEntry, 0, len(entries))
+	index := make(map[string]int, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		ext := path.Ext(name)
+		if e.IsDir() || (ext != ".json" && ext != ".job") {
+			continue
+		}
+
+		id, err := models.ParseID(strings.TrimSuffix(name, ext))
+		if err != nil || name != id+ext {
+			continue
+		}
+
+		fileInfo, err := e.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to get %q file info: %w", name, err)
+		}
+
+		i, ok := index[id]
+		if !ok {
+			i = len(result)
+			index[id] = i
+			result = append(result, models.StoreEntry{ID: id})
+		}
+
+		entry := &result[i]
+		entry.Size += fileInfo.Size()
+		if fileInfo.ModTime().After(entry.ModTime) {
+			entry.ModTime = fileInfo.ModTime()
+		}
+		if ext == ".json" {
+			entry.HasResult = true
+		}
+	}
+
+	return result, nil
+}
+
+// Ping checks the store directory is writable.
+func (s *Store) Ping() error {
+	f, err := os.CreateTemp(s.path, ".ping-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	_, errWrite := f.Write([]byte("ping"))
+	errClose := f.Close()
+	errRemove := os.Remove(f.Name())
+
+	if err := errors.Join(errWrite, errClose, errRemove); err != nil {
+		return fmt.Errorf("failed to write temporary file %q: %w", f.Name(), err)
+	}
+
+	return nil
+}
+
+func (s *Store) name(id string) string {
+	return filepath.Join(s.path, fmt.Sprintf("%s.json", id))
+}
+
+func (s *Store) jobName(id string) string {
+	return filepath.Join(s.path, fmt.Sprintf("%s.job", id))
+}
+
+func (s *Store) idempotencyKeyName(hash string) string {
+	return filepath.Join(s.path, fmt.Sprintf("%s.idem", hash))
+}
+
+func (s *Store) readIdempotencyKey(hash string) (models.IdempotencyKey, error) {
+	var key models.IdempotencyKey
+
+	b, err := os.ReadFile(s.idempotencyKeyName(hash))
+	if err != nil {
+		return key, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &key); err != nil {
+		return key, fmt.Errorf("failed to decode idempotency key from JSON: %w", err)
+	}
+
+	return key, nil
+}
+
+// removeIdempotencyKey deletes the key unless it was reused by another job after expiration.
+func (s *Store) removeIdempotencyKey(hash, id string) error {
+	unlock := s.locks.lock(idempotencyLockID(hash))
+	defer unlock()
+
+	key, err := s.readIdempotencyKey(hash)
+	if err != nil || key.JobID != id {
+		return nil
+	}
+
+	name := s.idempotencyKeyName(hash)
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove file %q: %w", name, err)
+	}
+
+	return nil
+}
+
+func (s *Store) schedulesPath() string {
+	return filepath.Join(s.path, "schedules")
+}
+
+func (s *Store) scheduleName(id string) string {
+	return filepath.Join(s.schedulesPath(), fmt.Sprintf("%s.json", id))
+}
+
+func (s *Store) readSchedule(id string) (models.Schedule, error) {
+	unlock := s.locks.rLock(scheduleLockID(id))
+	defer unlock()
+
+	var record scheduleRecord
+	b, err := os.ReadFile(s.scheduleName(id))
+	if err != nil {
+		return record.Schedule, fmt.Errorf("failed to read schedule %q: %w", id, err)
+	}
+
+	if err := json.Unmarshal(b, &record); err != nil {
+		return record.Schedule, fmt.Errorf("failed to decode schedule %q from JSON: %w", id, err)
+	}
+	record.Schedule.TokenHash = record.TokenHash
+
+	return record.Schedule, nil
+}
+
+// scheduleLockID keeps the locks of schedules apart from the ones of job IDs.
+func scheduleLockID(id string) string {
+	return "schedule:" + id
+}
+
+// idempotencyLockID keeps the locks of idempotency keys apart from the ones of IDs.
+func idempotencyLockID(hash string) string {
+	return "idem:" + hash
+}
+
+// write replaces the file atomically, so readers never see a partially written one.
+func (s *Store) write(name string, b []byte) error {
+	f, err := os.CreateTemp(s.path, fmt.Sprintf("%s-*.tmp", filepath.Base(name)))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("failed to write temporary file %q: %w", f.Name(), err)
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("failed to chmod temporary file %q: %w", f.Name(), err)
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("failed to close temporary file %q: %w", f.Name(), err)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("failed to rename %q to %q: %w", f.Name(), name, err)
+	}
+
+	return nil
+}
+
+func (f *file) Close() error {
+	defer f.unlock()
+
+	return f.File.Close()
 }
@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	"yegorov-boris/affise-test-task/internal/models"
+)
+
+func TestStore_Remove(t *testing.T) {
+	storePath := "./store/"
+	outputs := []models.Output{
+		{
+			URL:        "https://example.com",
+			StatusCode: 200,
+			Body:       "some text",
+		},
+	}
+	tests := []struct {
+		name        string
+		id          string
+		save        bool
+		read        bool
+		wantRemoved bool
+	}{
+		{
+			name:        "should return false when the result is not found",
+			id:          "1",
+			wantRemoved: false,
+		},
+		{
+			name:        "should remove a stored result",
+			id:          "2",
+			save:        true,
+			wantRemoved: true,
+		},
+		{
+			name:        "should wait for an active read before removing a stored result",
+			id:          "3",
+			save:        true,
+			read:        true,
+			wantRemoved: true,
+		},
+	}
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	s := New(slog.Default(), storePath)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.save {
+				s.Save(context.Background(), tt.id, outputs, "")
+			}
+
+			readDone := make(chan struct{})
+			if tt.read {
+				f, err := s.Open(tt.id)
+				if err != nil {
+					t.Errorf("Open() error = %v", err)
+					return
+				}
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					if _, err := io.ReadAll(f); err != nil {
+						t.Errorf("failed to read the result: %s", err)
+					}
+					close(readDone)
+					_ = f.Close()
+				}()
+			} else {
+				close(readDone)
+			}
+
+			removed, err := s.Remove(tt.id)
+			if err != nil {
+				t.Errorf("Remove() error = %v", err)
+				return
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("Remove() got = %v, want %v", removed, tt.wantRemoved)
+			}
+			select {
+			case <-readDone:
+			default:
+				t.Error("the result was removed before the active read finished")
+			}
+			if _, err := s.Open(tt.id); err == nil {
+				t.Error("the result should not be found after removal")
+			}
+		})
+	}
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestStore_Purge(t *testing.T) {
+	storePath := "./store/"
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	s := New(slog.Default(), storePath)
+	for _, id := range []string{"1", "2", "3"} {
+		s.Save(context.Background(), id, nil, "Request failed.")
+	}
+
+	t.Run("should remove all the stored results", func(t *testing.T) {
+		count, err := s.Purge()
+		if err != nil {
+			t.Errorf("Purge() error = %v", err)
+			return
+		}
+		if count != 3 {
+			t.Errorf("Purge() got = %v, want %v", count, 3)
+		}
+		entries, err := s.List()
+		if err != nil {
+			t.Errorf("List() error = %v", err)
+			return
+		}
+		if len(entries) != 0 {
+			t.Errorf("Expected no results after purge - got %v", entries)
+		}
+	})
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestStore_SaveIdempotencyKey(t *testing.T) {
+	storePath := "./store/"
+	now := time.Now()
+	tests := []struct {
+		name          string
+		stored        *models.IdempotencyKey
+		key           models.IdempotencyKey
+		replaceBefore time.Time
+		wantErr       bool
+		wantJobID     string
+	}{
+		{
+			name:          "should save a new key",
+			key:           models.IdempotencyKey{Hash: "a", JobID: "1", CreatedAt: now},
+			replaceBefore: now.Add(-time.Minute),
+			wantJobID:     "1",
+		},
+		{
+			name:          "should not replace a key saved within the window",
+			stored:        &models.IdempotencyKey{Hash: "b", JobID: "2", CreatedAt: now.Add(-time.Second)},
+			key:           models.IdempotencyKey{Hash: "b", JobID: "3", CreatedAt: now},
+			replaceBefore: now.Add(-time.Minute),
+			wantErr:       true,
+			wantJobID:     "2",
+		},
+		{
+			name:          "should replace an expired key",
+			stored:        &models.IdempotencyKey{Hash: "c", JobID: "4", CreatedAt: now.Add(-time.Hour)},
+			key:           models.IdempotencyKey{Hash: "c", JobID: "5", CreatedAt: now},
+			replaceBefore: now.Add(-time.Minute),
+			wantJobID:     "5",
+		},
+	}
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	s := New(slog.Default(), storePath)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stored != nil {
+				if err := s.SaveIdempotencyKey(*tt.stored, now); err != nil {
+					t.Errorf("failed to save the stored key: %s", err)
+					return
+				}
+			}
+
+			err := s.SaveIdempotencyKey(tt.key, tt.replaceBefore)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SaveIdempotencyKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !errors.Is(err, fs.ErrExist) {
+				t.Errorf("SaveIdempotencyKey() error = %v, want fs.ErrExist", err)
+			}
+
+			got, err := s.LoadIdempotencyKey(tt.key.Hash)
+			if err != nil {
+				t.Errorf("LoadIdempotencyKey() error = %v", err)
+				return
+			}
+			if got.JobID != tt.wantJobID {
+				t.Errorf("LoadIdempotencyKey() got job ID = %v, want %v", got.JobID, tt.wantJobID)
+			}
+		})
+	}
+
+	t.Run("should remove the key along with its job only", func(t *testing.T) {
+		for _, id := range []string{"4", "5"} {
+			if err := s.SaveJob(models.Job{ID: id, IdempotencyKeyHash: "c"}); err != nil {
+				t.Errorf("SaveJob() error = %v", err)
+				return
+			}
+		}
+
+		if _, err := s.Remove("4"); err != nil {
+			t.Errorf("Remove() error = %v", err)
+			return
+		}
+		if _, err := s.LoadIdempotencyKey("c"); err != nil {
+			t.Errorf("the key reused by another job should be kept, got error %v", err)
+		}
+
+		if _, err := s.Remove("5"); err != nil {
+			t.Errorf("Remove() error = %v", err)
+			return
+		}
+		if _, err := s.LoadIdempotencyKey("c"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("the key should be removed along with its job, got error %v", err)
+		}
+	})
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
+
+func TestStore_Save(t *testing.T) {
+	storePath := "./store/"
+	tests := []struct {
+		name    string
+		id      string
+		outputs []models.Output
+		errMsg  string
+		want    string
+	}{
+		{
+			name:    "should save the hash of the outputs to the job",
+			id:      "1",
+			outputs: []models.Output{{URL: "https://example.com", StatusCode: 200, Body: "some text"}},
+			want:    `[{"url":"https://example.com","status_code":200,"body":"some text"}]`,
+		},
+		{
+			name:   "should save the hash of the error message to the job",
+			id:     "2",
+			errMsg: "Failed.",
+			want:   "Failed.",
+		},
+	}
+	//set up
+	if err := os.Mkdir(storePath, 0777); err != nil {
+		t.Errorf("mkdir %q failed: %s", storePath, err)
+	}
+	s := New(slog.Default(), storePath)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.SaveJob(models.Job{ID: tt.id}); err != nil {
+				t.Errorf("SaveJob() error = %v", err)
+				return
+			}
+
+			s.Save(context.Background(), tt.id, tt.outputs, tt.errMsg)
+
+			job, err := s.LoadJob(tt.id)
+			if err != nil {
+				t.Errorf("LoadJob() error = %v", err)
+				return
+			}
+			if want := models.ContentHash(tt.want); job.ResultHash != want {
+				t.Errorf("ResultHash got = %v, want %v", job.ResultHash, want)
+			}
+		})
+	}
+
+	if err := os.RemoveAll(storePath); err != nil && err != os.ErrExist {
+		t.Errorf("rm %q failed: %s", storePath, err)
+	}
+}
@@ -0,0 +1,316 @@
+// Package client is a Go client of the multiplexer API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	accessTokenHeader      = "X-Access-Token"
+	assertionsPassedHeader = "X-Assertions-Passed"
+	idempotencyKeyHeader   = "Idempotency-Key"
+	replayedHeader         = "Idempotent-Replayed"
+)
+
+// ErrInProgress is returned for the results of the jobs still in progress.
+var ErrInProgress = errors.New("job is in progress")
+
+type (
+	Client struct {
+		baseURL    string
+		apiKey     string
+		httpClient *http.Client
+	}
+
+	Option func(*Client)
+
+	// Job is a created job, the token is required to get or cancel it.
+	Job struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+		// Replayed is set when an earlier job with the same idempotency key is returned
+		Replayed bool `json:"replayed,omitempty"`
+	}
+
+	SubmitOptions struct {
+		Normalize      bool
+		SortQuery      bool
+		Extract        []string
+		Select         []string
+		DropBody       bool
+		NoCache        bool
+		IdempotencyKey string
+		// CompareTo and CompareToken reference the job to detect changes against
+		CompareTo    string
+		CompareToken string
+		Diff         bool
+	}
+
+	// Result is a finished job, either its outputs or the error message of a failed job.
+	Result struct {
+		Outputs []Output `json:"outputs,omitempty"`
+		Error   string   `json:"error,omitempty"`
+		// AssertionsPassed is set for the jobs with assertions
+		AssertionsPassed *bool `json:"assertions_passed,omitempty"`
+	}
+
+	Output struct {
+		URL        string     `json:"url"`
+		StatusCode int        `json:"status_code"`
+		Body       string     `json:"body"`
+		BodyHash   string     `json:"body_hash,omitempty"`
+		Cached     bool       `json:"cached,omitempty"`
+		Age        int64      `json:"age,omitempty"`
+		Extracted  *Extracted `json:"extracted,omitempty"`
+		Verdict    *Verdict   `json:"verdict,omitempty"`
+		Change     *Change    `json:"change,omitempty"`
+	}
+
+	Extracted struct {
+		Title string            `json:"title,omitempty"`
+		Meta  map[string]string `json:"meta,omitempty"`
+		Links []string          `json:"links,omitempty"`
+		Text  string            `json:"text,omitempty"`
+		JSON  map[string]any    `json:"json,omitempty"`
+	}
+
+	Verdict struct {
+		Passed  bool     `json:"passed"`
+		Reasons []string `json:"reasons,omitempty"`
+	}
+
+	Change struct {
+		Changed            bool   `json:"changed"`
+		New                bool   `json:"new,omitempty"`
+		StatusChanged      bool   `json:"status_changed,omitempty"`
+		BodyChanged        bool   `json:"body_changed,omitempty"`
+		PreviousStatusCode int    `json:"previous_status_code,omitempty"`
+		BodyHash           string `json:"body_hash"`
+		PreviousBodyHash   string `json:"previous_body_hash,omitempty"`
+		Diff               string `json:"diff,omitempty"`
+	}
+
+	// APIError is a response with an unexpected status code.
+	APIError struct {
+		StatusCode int
+		Message    string
+	}
+)
+
+// New creates a client of the API at baseURL, e.g. http://127.0.0.1:8080/api/v1/links
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithAPIKey sends the key in the X-API-Key header.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// Failed reports whether the job failed or any of its assertions failed.
+func (r *Result) Failed() bool {
+	return len(r.Error) != 0 || (r.AssertionsPassed != nil && !*r.AssertionsPassed)
+}
+
+// Submit creates a job fetching the links.
+func (c *Client) Submit(ctx context.Context, links []string, opts SubmitOptions) (Job, error) {
+	var job Job
+
+	body, err := json.Marshal(links)
+	if err != nil {
+		return job, fmt.Errorf("failed to JSON encode links: %w", err)
+	}
+
+	query := url.Values{}
+	setBool(query, "normalize", opts.Normalize)
+	setBool(query, "sort_query", opts.SortQuery)
+	setBool(query, "drop_body", opts.DropBody)
+	setBool(query, "diff", opts.Diff)
+	if len(opts.Extract) != 0 {
+		query.Set("extract", strings.Join(opts.Extract, ","))
+	}
+	for _, path := range opts.Select {
+		query.Add("select", path)
+	}
+	if len(opts.CompareTo) != 0 {
+		query.Set("compare_to", opts.CompareTo)
+		query.Set("compare_token", opts.CompareToken)
+	}
+
+	r, err := c.newRequest(ctx, http.MethodPost, "/links", query, bytes.NewReader(body))
+	if err != nil {
+		return job, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if opts.NoCache {
+		r.Header.Set("Cache-Control", "no-cache")
+	}
+	if len(opts.IdempotencyKey) != 0 {
+		r.Header.Set(idempotencyKeyHeader, opts.IdempotencyKey)
+	}
+
+	resp, data, err := c.do(r)
+	if err != nil {
+		return job, err
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return job, newAPIError(resp, data)
+	}
+
+	job.ID = strings.TrimSpace(string(data))
+	job.Token = resp.Header.Get(accessTokenHeader)
+	job.Replayed = resp.Header.Get(replayedHeader) == "true"
+
+	return job, nil
+}
+
+// Get returns the result of the finished job or ErrInProgress.
+func (c *Client) Get(ctx context.Context, id, token string) (*Result, error) {
+	r, err := c.newRequest(ctx, http.MethodGet, "/links/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(accessTokenHeader, token)
+
+	resp, data, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, data)
+	}
+
+	// only finished results have ETags
+	if len(resp.Header.Get("ETag")) == 0 {
+		return nil, ErrInProgress
+	}
+
+	var result Result
+	if passed, err := strconv.ParseBool(resp.Header.Get(assertionsPassedHeader)); err == nil {
+		result.AssertionsPassed = &passed
+	}
+
+	// failed jobs store the error message instead of outputs
+	if err := json.Unmarshal(data, &result.Outputs); err != nil {
+		result.Error = strings.TrimSpace(string(data))
+	}
+
+	return &result, nil
+}
+
+// Wait polls the job every interval until it is finished.
+func (c *Client) Wait(ctx context.Context, id, token string, interval time.Duration) (*Result, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := c.Get(ctx, id, token)
+		if !errors.Is(err, ErrInProgress) {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel cancels the job in progress or removes the result of the finished one.
+func (c *Client) Cancel(ctx context.Context, id, token string) error {
+	r, err := c.newRequest(ctx, http.MethodDelete, "/links/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(accessTokenHeader, token)
+
+	resp, data, err := c.do(r)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		return newAPIError(resp, data)
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := c.baseURL + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if len(c.apiKey) != 0 {
+		r.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	return r, nil
+}
+
+func (c *Client) do(r *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp, data, nil
+}
+
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(data)),
+	}
+}
+
+func setBool(query url.Values, name string, value bool) {
+	if value {
+		query.Set(name, "true")
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Submit(t *testing.T) {
+	var got struct {
+		query, body, apiKey, idempotencyKey string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got.query, got.body = r.URL.RawQuery, string(b)
+		got.apiKey, got.idempotencyKey = r.Header.Get("X-API-Key"), r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/links/links" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set(accessTokenHeader, "secret")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = fmt.Fprint(w, "7")
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/api/v1/links/", WithAPIKey("key"))
+	job, err := c.Submit(context.Background(), []string{"https://example.com"}, SubmitOptions{
+		Normalize:      true,
+		Extract:        []string{"title", "meta"},
+		Select:         []string{"a.b", "c"},
+		IdempotencyKey: "once",
+	})
+	if err != nil {
+		t.Errorf("Submit() error = %v", err)
+		return
+	}
+	if want := (Job{ID: "7", Token: "secret"}); job != want {
+		t.Errorf("Submit() got = %+v, want %+v", job, want)
+	}
+	if want := "extract=title%2Cmeta&normalize=true&select=a.b&select=c"; got.query != want {
+		t.Errorf("query got = %q, want %q", got.query, want)
+	}
+	if want := `["https://example.com"]`; got.body != want {
+		t.Errorf("body got = %q, want %q", got.body, want)
+	}
+	if got.apiKey != "key" || got.idempotencyKey != "once" {
+		t.Errorf("headers got = %q, %q", got.apiKey, got.idempotencyKey)
+	}
+}
+
+func TestClient_Get(t *testing.T) {
+	passed := false
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    *Result
+		wantErr error
+	}{
+		{
+			name: "should return outputs of a finished job",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"hash"`)
+				w.Header().Set(assertionsPassedHeader, "false")
+				_, _ = fmt.Fprint(w, `[{"url":"https://example.com","status_code":200,"body":"ok","verdict":{"passed":false}}]`)
+			},
+			want: &Result{
+				Outputs:          []Output{{URL: "https://example.com", StatusCode: 200, Body: "ok", Verdict: &Verdict{}}},
+				AssertionsPassed: &passed,
+			},
+		},
+		{
+			name: "should return the error of a failed job",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"hash"`)
+				_, _ = fmt.Fprint(w, "Request to https://example.com failed.")
+			},
+			want: &Result{Error: "Request to https://example.com failed."},
+		},
+		{
+			name: "should report a job in progress",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, "Your request is in progress. Please, try a bit later.")
+			},
+			wantErr: ErrInProgress,
+		},
+		{
+			name: "should return API errors",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Invalid access token.", http.StatusForbidden)
+			},
+			wantErr: &APIError{StatusCode: http.StatusForbidden, Message: "Invalid access token."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			got, err := New(srv.URL).Get(context.Background(), "1", "token")
+			var apiErr *APIError
+			if errors.As(tt.wantErr, &apiErr) {
+				if !reflect.DeepEqual(err, tt.wantErr) {
+					t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) < 3 {
+			_, _ = fmt.Fprint(w, "Your request is in progress. Please, try a bit later.")
+			return
+		}
+		w.Header().Set("ETag", `"hash"`)
+		_, _ = fmt.Fprint(w, `[{"url":"https://example.com","status_code":200,"body":"ok"}]`)
+	}))
+	defer srv.Close()
+
+	var events []string
+	result, err := New(srv.URL).Watch(context.Background(), "1", "token", 10*time.Millisecond, func(e Event) {
+		events = append(events, e.Type)
+	})
+	if err != nil {
+		t.Errorf("Watch() error = %v", err)
+		return
+	}
+	if want := []string{EventInProgress, EventOutput, EventFinished}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Watch() events = %v, want %v", events, want)
+	}
+	if result.Failed() {
+		t.Errorf("Watch() got failed result %+v", result)
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	EventInProgress = "in_progress"
+	EventOutput     = "output"
+	EventFinished   = "finished"
+	EventFailed     = "failed"
+)
+
+// Event is a change of the job state. The API has no event stream, so events are observed by polling.
+type Event struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	JobID  string    `json:"job_id"`
+	Output *Output   `json:"output,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Watch polls the job every interval until it is finished, calling handle on every event:
+// in_progress while the job runs, output for every output and either finished or failed in the end.
+func (c *Client) Watch(ctx context.Context, id, token string, interval time.Duration, handle func(Event)) (*Result, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	inProgress := false
+	for {
+		result, err := c.Get(ctx, id, token)
+		if err == nil {
+			for i := range result.Outputs {
+				handle(Event{Type: EventOutput, Time: time.Now(), JobID: id, Output: &result.Outputs[i]})
+			}
+
+			last := Event{Type: EventFinished, Time: time.Now(), JobID: id}
+			if result.Failed() {
+				last.Type, last.Error = EventFailed, result.Error
+				if len(last.Error) == 0 {
+					last.Error = "assertions failed"
+				}
+			}
+			handle(last)
+
+			return result, nil
+		}
+
+		if !errors.Is(err, ErrInProgress) {
+			return nil, err
+		}
+
+		if !inProgress {
+			inProgress = true
+			handle(Event{Type: EventInProgress, Time: time.Now(), JobID: id})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
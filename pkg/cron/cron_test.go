@@ -0,0 +1,97 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		name    string
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "should match every minute",
+			expr: "* * * * *",
+			want: time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC),
+		},
+		{
+			name: "should match steps",
+			expr: "*/15 * * * *",
+			want: time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "should match lists and ranges in the next day",
+			expr: "0,30 8-9 * * *",
+			want: time.Date(2024, time.February, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should skip months without the day",
+			expr: "0 0 30 * *",
+			want: time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should match names of months and weekdays",
+			expr: "0 12 * feb mon-tue",
+			want: time.Date(2024, time.February, 5, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should match 7 as Sunday",
+			expr: "0 0 * * 7",
+			want: time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should match either restricted day of month or day of week",
+			expr: "0 0 15 * fri",
+			want: time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should support macros",
+			expr: "@monthly",
+			want: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "should match leap days",
+			expr: "0 0 29 2 *",
+			want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "should fail when no day ever matches",
+			expr:    "0 0 31 2 *",
+			wantErr: true,
+		},
+		{
+			name:    "should fail for a wrong number of fields",
+			expr:    "* * * *",
+			wantErr: true,
+		},
+		{
+			name:    "should fail for a value out of range",
+			expr:    "60 * * * *",
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an invalid step",
+			expr:    "*/0 * * * *",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err == nil {
+				var got time.Time
+				if got, err = s.Next(from); err == nil && !got.Equal(tt.want) {
+					t.Errorf("Next() got = %v, want %v", got, tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() or Next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
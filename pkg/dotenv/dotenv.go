@@ -1,46 +1,349 @@
+/*
+Package dotenv parses .env files:
+
+	# comment
+	export KEY=value # inline comment, needs a whitespace before "#"
+	URL=http://host/#anchor
+	SINGLE='literal, no escapes and no ${interpolation}'
+	DOUBLE="escapes \" \\ \n \r \t \$ and ${interpolation}"
+	MULTILINE="first line
+	second line"
+	WITH_DEFAULT=${UNSET:-default}
+
+${VAR} is replaced by the value of VAR set earlier in the file, otherwise by the env var,
+${VAR:-default} uses default when VAR is unset or empty. Both LF and CRLF line endings are supported.
+*/
 package dotenv
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 )
 
-/*
-Load parses simplified .env format:
-a line either is empty or has format key=value
-where key and value are any string,
-no interpolation,
-no "newline" symbols in keys and values.
-*/
+var (
+	escapes = map[byte]string{'n': "\n", 'r': "\r", 't': "\t", '"': "\"", '\\': "\\", '$': "$"}
+
+	// loaded keeps the keys set by Load, so a reloaded file overrides them, but not the real environment.
+	loaded = make(map[string]struct{})
+	m      sync.Mutex
+)
+
+// Load sets env vars from the file. Env vars set outside of the file are not overridden,
+// the ones set by a previous Load are, so the file can be reloaded.
 func Load(name string) error {
+	return load(name, false)
+}
+
+// Overload sets env vars from the file overriding the existing ones.
+func Overload(name string) error {
+	return load(name, true)
+}
+
+// Read parses the file without changing env vars.
+func Read(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .env file: %w", err)
+	}
+
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse parses .env content without changing env vars.
+func Parse(r io.Reader) (map[string]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .env content: %w", err)
+	}
+
+	_, values, err := parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .env content: %w", err)
+	}
+
+	return values, nil
+}
+
+func load(name string, override bool) error {
 	b, err := os.ReadFile(name)
 	if err != nil {
 		return fmt.Errorf("failed to read .env file: %w", err)
 	}
 
-	if err := parse(b); err != nil {
+	keys, values, err := parse(b)
+	if err != nil {
 		return fmt.Errorf("failed to parse .env file: %w", err)
 	}
 
+	m.Lock()
+	defer m.Unlock()
+
+	for _, key := range keys {
+		_, isLoaded := loaded[key]
+		if _, isSet := os.LookupEnv(key); isSet && !isLoaded && !override {
+			continue
+		}
+
+		if err := os.Setenv(key, values[key]); err != nil {
+			return fmt.Errorf("failed to set env var %q: %w", key, err)
+		}
+		loaded[key] = struct{}{}
+	}
+
 	return nil
 }
 
-func parse(b []byte) error {
-	for _, line := range strings.Split(string(b), "\n") {
-		if len(line) == 0 {
+// parse returns the keys in order of appearance and their values.
+func parse(b []byte) ([]string, map[string]string, error) {
+	p := &parser{
+		src:    strings.ReplaceAll(string(b), "\r\n", "\n"),
+		line:   1,
+		values: make(map[string]string),
+	}
+
+	var keys []string
+	for {
+		p.skip(" \t\n")
+		if p.eof() {
+			return keys, p.values, nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
 			continue
 		}
 
-		pair := strings.SplitN(line, "=", 2)
-		if len(pair) != 2 {
-			return fmt.Errorf("unsupproted key-value pair %q in .env", line)
+		key, value, err := p.pair()
+		if err != nil {
+			return nil, nil, err
 		}
 
-		if err := os.Setenv(pair[0], pair[1]); err != nil {
-			return fmt.Errorf("failed to set env var %q: %w", pair[0], err)
+		if _, ok := p.values[key]; !ok {
+			keys = append(keys, key)
 		}
+		p.values[key] = value
 	}
+}
 
-	return nil
+type parser struct {
+	src    string
+	pos    int
+	line   int
+	values map[string]string
+}
+
+func (p *parser) pair() (string, string, error) {
+	line := p.line
+	key := p.key()
+	if key == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skip(" \t")
+		key = p.key()
+	}
+
+	if !isValidKey(key) {
+		return "", "", fmt.Errorf("line %d: invalid key %q", line, key)
+	}
+
+	p.skip(" \t")
+	if p.eof() || p.peek() != '=' {
+		return "", "", fmt.Errorf("line %d: expected \"=\" after key %q", line, key)
+	}
+	p.pos++
+	p.skip(" \t")
+
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case p.eof():
+		return key, "", nil
+	case p.peek() == '\'':
+		value, err = p.singleQuoted()
+	case p.peek() == '"':
+		value, err = p.doubleQuoted()
+	default:
+		value, err = p.unquoted()
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
+func (p *parser) key() string {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune("= \t\n#", rune(p.peek())) {
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+func (p *parser) singleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end == -1 {
+		return "", fmt.Errorf("line %d: unterminated single quoted value", line)
+	}
+
+	value := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+
+	return value, p.endOfValue()
+}
+
+func (p *parser) doubleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("line %d: unterminated double quoted value", line)
+		}
+
+		c := p.peek()
+		switch {
+		case c == '"':
+			p.pos++
+
+			return sb.String(), p.endOfValue()
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			escaped, ok := escapes[p.peek()]
+			if !ok {
+				return "", fmt.Errorf("line %d: unsupported escape sequence \"\\%c\"", p.line, p.peek())
+			}
+			sb.WriteString(escaped)
+			p.pos++
+		case c == '$' && strings.HasPrefix(p.src[p.pos:], "${"):
+			value, err := p.interpolate()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) unquoted() (string, error) {
+	var sb strings.Builder
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		if c == '#' && p.pos > 0 && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			p.skipLine()
+			break
+		}
+
+		if c == '$' && strings.HasPrefix(p.src[p.pos:], "${") {
+			value, err := p.interpolate()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			continue
+		}
+
+		sb.WriteByte(c)
+		p.pos++
+	}
+
+	return strings.TrimRight(sb.String(), " \t"), nil
+}
+
+// interpolate replaces ${VAR} or ${VAR:-default} at the current position.
+func (p *parser) interpolate() (string, error) {
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end == -1 || strings.Contains(p.src[p.pos:p.pos+end], "\n") {
+		return "", fmt.Errorf("line %d: unterminated variable reference", p.line)
+	}
+
+	expr := p.src[p.pos+2 : p.pos+end]
+	p.pos += end + 1
+
+	name, defaultValue, hasDefault := strings.Cut(expr, ":-")
+	if !isValidKey(name) {
+		return "", fmt.Errorf("line %d: invalid variable name %q", p.line, name)
+	}
+
+	value, ok := p.values[name]
+	if !ok {
+		value = os.Getenv(name)
+	}
+	if len(value) == 0 && hasDefault {
+		value = defaultValue
+	}
+
+	return value, nil
+}
+
+// endOfValue allows only whitespaces and a comment after a quoted value.
+func (p *parser) endOfValue() error {
+	p.skip(" \t")
+	if p.eof() || p.peek() == '\n' {
+		return nil
+	}
+
+	if p.peek() == '#' {
+		p.skipLine()
+		return nil
+	}
+
+	return fmt.Errorf("line %d: unexpected characters after quoted value", p.line)
+}
+
+func (p *parser) skip(chars string) {
+	for !p.eof() && strings.IndexByte(chars, p.peek()) != -1 {
+		if p.peek() == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipLine moves to the end of the line, the line break is not consumed.
+func (p *parser) skipLine() {
+	if end := strings.IndexByte(p.src[p.pos:], '\n'); end != -1 {
+		p.pos += end
+		return
+	}
+
+	p.pos = len(p.src)
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func isValidKey(key string) bool {
+	if len(key) == 0 || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+
+	for _, c := range key {
+		if !(c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return true
 }
@@ -7,43 +7,165 @@ import (
 )
 
 func Test_parse(t *testing.T) {
-	s := []string{
-		"FOO=bar",
-		"ABC_DEF=1",
-		"",
-		"TEST=2s",
-	}
-	expected := map[string]string{
-		"FOO":     "bar",
-		"ABC_DEF": "1",
-		"TEST":    "2s",
-	}
-	type args struct {
-		b []byte
-	}
+	t.Setenv("DOTENV_TEST_HOST", "example.com")
 	tests := []struct {
 		name    string
-		args    args
-		wantErr bool
+		lines   []string
+		want    map[string]string
+		wantErr string
 	}{
 		{
-			name: "should parse a simplified .env file (see documentation for Load function)",
-			args: args{
-				b: []byte(strings.Join(s, "\n")),
+			name: "should parse a simplified .env file",
+			lines: []string{
+				"FOO=bar",
+				"ABC_DEF=1",
+				"",
+				"TEST=2s",
+			},
+			want: map[string]string{
+				"FOO":     "bar",
+				"ABC_DEF": "1",
+				"TEST":    "2s",
+			},
+		},
+		{
+			name: "should skip comments and export prefixes",
+			lines: []string{
+				"# comment",
+				"  # indented comment",
+				"export FOO=bar # inline comment",
+				"URL=http://host/#anchor",
+				"EMPTY=",
+			},
+			want: map[string]string{
+				"FOO":   "bar",
+				"URL":   "http://host/#anchor",
+				"EMPTY": "",
+			},
+		},
+		{
+			name: "should unquote values",
+			lines: []string{
+				`SINGLE='a \n ${FOO} # b'`,
+				`DOUBLE="a \"b\" \\ \n\t\$ # c" # comment`,
+				`MULTILINE="first`,
+				`second"`,
+				`AFTER=1`,
+			},
+			want: map[string]string{
+				"SINGLE":    `a \n ${FOO} # b`,
+				"DOUBLE":    "a \"b\" \\ \n\t$ # c",
+				"MULTILINE": "first\nsecond",
+				"AFTER":     "1",
+			},
+		},
+		{
+			name: "should interpolate variables",
+			lines: []string{
+				"PORT=8080",
+				"URL=http://${DOTENV_TEST_HOST}:${PORT}",
+				`QUOTED="${PORT}/${DOTENV_TEST_UNSET:-default}"`,
+			},
+			want: map[string]string{
+				"PORT":   "8080",
+				"URL":    "http://example.com:8080",
+				"QUOTED": "8080/default",
+			},
+		},
+		{
+			name:  "should support CRLF line endings",
+			lines: []string{"FOO=bar\r", "BAZ=\"a\r", "b\"\r", ""},
+			want: map[string]string{
+				"FOO": "bar",
+				"BAZ": "a\nb",
 			},
-			wantErr: false,
+		},
+		{
+			name:    "should report the line of a pair without \"=\"",
+			lines:   []string{"FOO=bar", "", "BAZ"},
+			wantErr: "line 3",
+		},
+		{
+			name:    "should report the line of an unterminated quoted value",
+			lines:   []string{"FOO=bar", `BAZ="a`, "b"},
+			wantErr: "line 2",
+		},
+		{
+			name:    "should report the line of an invalid key",
+			lines:   []string{"FOO=bar", "1FOO=bar"},
+			wantErr: "line 2",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := parse(tt.args.b); (err != nil) != tt.wantErr {
-				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			_, got, err := parse([]byte(strings.Join(tt.lines, "\n")))
+			if (err != nil) != (len(tt.wantErr) != 0) {
+				t.Errorf("parse() error = %v, wantErr %q", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
 			}
-			for key, value := range expected {
-				if os.Getenv(key) != value {
-					t.Errorf("Wrong env var %q: expected %q - got %q", key, expected[key], value)
+			if len(got) != len(tt.want) {
+				t.Errorf("parse() got %d values, want %d", len(got), len(tt.want))
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("Wrong value of %q: expected %q - got %q", key, value, got[key])
 				}
 			}
 		})
 	}
 }
+
+func TestLoad(t *testing.T) {
+	name := "./test.env"
+	t.Setenv("DOTENV_TEST_SET", "env")
+	if err := os.WriteFile(name, []byte("DOTENV_TEST_SET=file\nDOTENV_TEST_NEW=file\n"), 0644); err != nil {
+		t.Errorf("failed to write %q: %s", name, err)
+	}
+
+	t.Run("should not override env vars set outside of the file", func(t *testing.T) {
+		if err := Load(name); err != nil {
+			t.Errorf("Load() error = %v", err)
+			return
+		}
+		if got := os.Getenv("DOTENV_TEST_SET"); got != "env" {
+			t.Errorf("DOTENV_TEST_SET = %q, want %q", got, "env")
+		}
+		if got := os.Getenv("DOTENV_TEST_NEW"); got != "file" {
+			t.Errorf("DOTENV_TEST_NEW = %q, want %q", got, "file")
+		}
+	})
+
+	t.Run("should override env vars set by the previous load", func(t *testing.T) {
+		if err := os.WriteFile(name, []byte("DOTENV_TEST_SET=file\nDOTENV_TEST_NEW=reloaded\n"), 0644); err != nil {
+			t.Errorf("failed to write %q: %s", name, err)
+		}
+		if err := Load(name); err != nil {
+			t.Errorf("Load() error = %v", err)
+			return
+		}
+		if got := os.Getenv("DOTENV_TEST_NEW"); got != "reloaded" {
+			t.Errorf("DOTENV_TEST_NEW = %q, want %q", got, "reloaded")
+		}
+	})
+
+	t.Run("should override all env vars by Overload", func(t *testing.T) {
+		if err := Overload(name); err != nil {
+			t.Errorf("Overload() error = %v", err)
+			return
+		}
+		if got := os.Getenv("DOTENV_TEST_SET"); got != "file" {
+			t.Errorf("DOTENV_TEST_SET = %q, want %q", got, "file")
+		}
+	})
+
+	_ = os.Unsetenv("DOTENV_TEST_NEW")
+	if err := os.Remove(name); err != nil {
+		t.Errorf("rm %q failed: %s", name, err)
+	}
+}
@@ -0,0 +1,120 @@
+package extract
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	base, err := url.Parse("https://example.com/dir/page.html")
+	if err != nil {
+		t.Errorf("failed to parse base URL: %s", err)
+		return
+	}
+	tests := []struct {
+		name string
+		src  string
+		want Document
+	}{
+		{
+			name: "should extract the title and meta tags",
+			src: `<!DOCTYPE html><html><head><TITLE> Foo &amp;
+				Bar </TITLE><meta name="Description" content="About foo">
+				<meta property=og:title content='Foo'><meta name="description" content="ignored"></head></html>`,
+			want: Document{
+				Title: "Foo & Bar",
+				Meta:  map[string]string{"description": "About foo", "og:title": "Foo"},
+			},
+		},
+		{
+			name: "should resolve links without duplicates and fragments",
+			src: `<a href="/a">A</a> <a href="b#top">B</a> <A HREF="https://example.com/a">A</A>
+				<a href="mailto:foo@example.com">mail</a> <a href="javascript:void(0)">js</a> <a>no</a>href`,
+			want: Document{
+				Links: []string{"https://example.com/a", "https://example.com/dir/b"},
+				Text:  "A B A mail js nohref",
+			},
+		},
+		{
+			name: "should resolve links against the base tag",
+			src:  `<head><base href="https://cdn.example.com/x/"></head><a href="y">y</a>`,
+			want: Document{
+				Links: []string{"https://cdn.example.com/x/y"},
+				Text:  "y",
+			},
+		},
+		{
+			name: "should extract the visible text only",
+			src: `<html><head><style>p { color: red }</style></head><body>
+				<script>if (a < b && "</p>") {}</script><p>Hello,&nbsp;<b>wor</b>ld!</p><!-- <p>comment</p> -->
+				<noscript>enable JS</noscript><div>Second<br>line</div><template><p>hidden</p></template>
+				<textarea>a &lt; b</textarea></body></html>`,
+			want: Document{
+				Text: "Hello, world! Second line a < b",
+			},
+		},
+		{
+			name: "should not fail on malformed markup",
+			src:  `1 < 2 and <p class="unterminated>text`,
+			want: Document{
+				Text: "1 < 2 and",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseHTML(tt.src, base); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHTML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectJSON(t *testing.T) {
+	src := []byte(`{"items": [{"name": "foo", "id": 12345678901234567890}], "meta": {"total": 1, "next": null}}`)
+	tests := []struct {
+		name    string
+		src     []byte
+		paths   []string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "should select values by dotted paths",
+			src:   src,
+			paths: []string{"items.0.name", "items.0.id", "meta.next", "meta"},
+			want: map[string]any{
+				"items.0.name": "foo",
+				"items.0.id":   json.Number("12345678901234567890"),
+				"meta.next":    nil,
+				"meta":         map[string]any{"total": json.Number("1"), "next": nil},
+			},
+		},
+		{
+			name:  "should omit paths not found",
+			src:   src,
+			paths: []string{"items.1.name", "items.x", "meta.total.value", "foo"},
+			want:  map[string]any{},
+		},
+		{
+			name:    "should fail for invalid JSON",
+			src:     []byte("<html>"),
+			paths:   []string{"foo"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectJSON(tt.src, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SelectJSON() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectJSON() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
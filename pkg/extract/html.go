@@ -0,0 +1,149 @@
+// Package extract pulls data out of fetched HTML and JSON documents.
+package extract
+
+import (
+	"net/url"
+	"strings"
+)
+
+var (
+	// invisibleElements are not rendered, so their content is not a part of the visible text
+	invisibleElements = map[string]struct{}{
+		"head":     {},
+		"script":   {},
+		"style":    {},
+		"noscript": {},
+		"template": {},
+		"svg":      {},
+		"iframe":   {},
+		"object":   {},
+	}
+
+	// blockElements separate words of the visible text
+	blockElements = map[string]struct{}{
+		"address": {}, "article": {}, "aside": {}, "blockquote": {}, "br": {}, "dd": {}, "div": {},
+		"dl": {}, "dt": {}, "fieldset": {}, "figcaption": {}, "figure": {}, "footer": {}, "form": {},
+		"h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {}, "header": {}, "hr": {}, "li": {},
+		"main": {}, "nav": {}, "ol": {}, "option": {}, "p": {}, "pre": {}, "section": {}, "table": {},
+		"td": {}, "th": {}, "tr": {}, "ul": {}, "img": {}, "input": {}, "button": {}, "label": {},
+	}
+)
+
+// Document is the data extracted from an HTML page.
+type Document struct {
+	Title string
+	// Meta is the content of meta tags by their name, property or http-equiv, the first tag wins
+	Meta map[string]string
+	// Links are the absolute HTTP links of anchors in order of appearance, without duplicates
+	Links []string
+	// Text is the visible text with collapsed whitespaces
+	Text string
+}
+
+// ParseHTML extracts the data of the page, relative links are resolved against base or the <base> tag of the page.
+func ParseHTML(src string, base *url.URL) Document {
+	var (
+		doc       Document
+		text      strings.Builder
+		inTitle   bool
+		hasTitle  bool
+		hidden    int
+		seenLinks = make(map[string]struct{})
+	)
+
+	t := NewTokenizer(src)
+	for {
+		token, ok := t.Next()
+		if !ok {
+			break
+		}
+
+		switch token.Type {
+		case TextToken:
+			if inTitle {
+				inTitle = false
+				if !hasTitle {
+					doc.Title = collapseSpaces(token.Data)
+					hasTitle = true
+				}
+				continue
+			}
+			if hidden == 0 {
+				text.WriteString(token.Data)
+			}
+		case StartTagToken, SelfClosingTagToken:
+			switch token.Data {
+			case "title":
+				inTitle = token.Type == StartTagToken
+			case "base":
+				if href, ok := token.Attrs["href"]; ok {
+					if u, err := resolve(base, href); err == nil {
+						base = u
+					}
+				}
+			case "meta":
+				if content, ok := token.Attrs["content"]; ok {
+					for _, attr := range []string{"name", "property", "http-equiv"} {
+						name := strings.ToLower(strings.TrimSpace(token.Attrs[attr]))
+						if len(name) == 0 {
+							continue
+						}
+						if doc.Meta == nil {
+							doc.Meta = make(map[string]string)
+						}
+						if _, ok := doc.Meta[name]; !ok {
+							doc.Meta[name] = content
+						}
+					}
+				}
+			case "a", "area":
+				if href, ok := token.Attrs["href"]; ok {
+					if u, err := resolve(base, href); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+						u.Fragment = ""
+						link := u.String()
+						if _, ok := seenLinks[link]; !ok {
+							seenLinks[link] = struct{}{}
+							doc.Links = append(doc.Links, link)
+						}
+					}
+				}
+			}
+
+			if _, ok := invisibleElements[token.Data]; ok && token.Type == StartTagToken {
+				hidden++
+			}
+			if _, ok := blockElements[token.Data]; ok {
+				text.WriteByte(' ')
+			}
+		case EndTagToken:
+			if _, ok := invisibleElements[token.Data]; ok && hidden > 0 {
+				hidden--
+			}
+			if _, ok := blockElements[token.Data]; ok {
+				text.WriteByte(' ')
+			}
+		}
+	}
+
+	doc.Text = collapseSpaces(text.String())
+
+	return doc
+}
+
+func resolve(base *url.URL, href string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		return u, nil
+	}
+
+	return base.ResolveReference(u), nil
+}
+
+// collapseSpaces also replaces non-breaking spaces, so the text is easy to search.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\u00a0", " ")), " ")
+}
@@ -0,0 +1,57 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectJSON returns the values found by dotted paths like "items.0.name", array elements are selected by index.
+// Paths not found in the document are omitted. Numbers are returned as json.Number to keep their precision.
+func SelectJSON(src []byte, paths []string) (map[string]any, error) {
+	var doc any
+
+	d := json.NewDecoder(bytes.NewReader(src))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	selected := make(map[string]any, len(paths))
+	for _, path := range paths {
+		if value, ok := selectPath(doc, path); ok {
+			selected[path] = value
+		}
+	}
+
+	return selected, nil
+}
+
+func selectPath(value any, path string) (any, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,273 @@
+package extract
+
+import (
+	"html"
+	"strings"
+)
+
+type TokenType int
+
+const (
+	TextToken TokenType = iota
+	StartTagToken
+	EndTagToken
+	SelfClosingTagToken
+	CommentToken
+	DoctypeToken
+)
+
+// rawTextElements have their content read as text until the matching end tag.
+var rawTextElements = map[string]struct{}{
+	"script":   {},
+	"style":    {},
+	"textarea": {},
+	"title":    {},
+	"xmp":      {},
+	"iframe":   {},
+	"noembed":  {},
+	"noframes": {},
+}
+
+// Token is an HTML token. Tag and attribute names are lower-cased, text and attribute values are unescaped.
+type Token struct {
+	Type  TokenType
+	Data  string // tag name, text or comment
+	Attrs map[string]string
+}
+
+// Tokenizer splits HTML into tokens. It is lenient like browsers are:
+// malformed markup is returned as text instead of failing.
+type Tokenizer struct {
+	src string
+	pos int
+	// rawTag is the name of the raw text element whose content is read next
+	rawTag string
+}
+
+func NewTokenizer(src string) *Tokenizer {
+	return &Tokenizer{
+		src: src,
+	}
+}
+
+// Next returns the next token and false at the end of input.
+func (t *Tokenizer) Next() (Token, bool) {
+	if t.pos >= len(t.src) {
+		return Token{}, false
+	}
+
+	if len(t.rawTag) != 0 {
+		return t.rawText(), true
+	}
+
+	if t.src[t.pos] != '<' {
+		return t.text(), true
+	}
+
+	rest := t.src[t.pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		return t.until("<!--", "-->", CommentToken), true
+	case strings.HasPrefix(rest, "<![CDATA["):
+		return t.until("<![CDATA[", "]]>", TextToken), true
+	case len(rest) > 2 && rest[1] == '!':
+		token := t.until("<!", ">", CommentToken)
+		if strings.HasPrefix(strings.ToLower(token.Data), "doctype") {
+			token.Type = DoctypeToken
+			token.Data = strings.TrimSpace(token.Data[len("doctype"):])
+		}
+		return token, true
+	case len(rest) > 1 && rest[1] == '?':
+		return t.until("<?", ">", CommentToken), true
+	case len(rest) > 2 && rest[1] == '/' && isLetter(rest[2]):
+		return t.endTag(), true
+	case len(rest) > 1 && isLetter(rest[1]):
+		return t.startTag(), true
+	}
+
+	// a "<" not starting a tag is text
+	t.pos++
+	token := t.text()
+	token.Data = "<" + token.Data
+
+	return token, true
+}
+
+func (t *Tokenizer) text() Token {
+	end := strings.IndexByte(t.src[t.pos:], '<')
+	if end == -1 {
+		end = len(t.src) - t.pos
+	}
+
+	data := t.src[t.pos : t.pos+end]
+	t.pos += end
+
+	return Token{Type: TextToken, Data: html.UnescapeString(data)}
+}
+
+func (t *Tokenizer) rawText() Token {
+	tag := t.rawTag
+	t.rawTag = ""
+
+	end := len(t.src)
+	for i := t.pos; i+2+len(tag) <= len(t.src); i++ {
+		if t.src[i] != '<' || t.src[i+1] != '/' || !equalFoldASCII(t.src[i+2:i+2+len(tag)], tag) {
+			continue
+		}
+
+		after := i + 2 + len(tag)
+		if after == len(t.src) || strings.IndexByte(" \t\n\r\f/>", t.src[after]) != -1 {
+			end = i
+			break
+		}
+	}
+
+	data := t.src[t.pos:end]
+	t.pos = end
+	if tag != "script" && tag != "style" {
+		data = html.UnescapeString(data)
+	}
+
+	return Token{Type: TextToken, Data: data}
+}
+
+func (t *Tokenizer) until(open, end string, tokenType TokenType) Token {
+	start := t.pos + len(open)
+	i := strings.Index(t.src[start:], end)
+	if i == -1 {
+		t.pos = len(t.src)
+
+		return Token{Type: tokenType, Data: t.src[start:]}
+	}
+
+	t.pos = start + i + len(end)
+
+	return Token{Type: tokenType, Data: t.src[start : start+i]}
+}
+
+func (t *Tokenizer) endTag() Token {
+	t.pos += 2
+	name := t.name()
+	if i := strings.IndexByte(t.src[t.pos:], '>'); i != -1 {
+		t.pos += i + 1
+	} else {
+		t.pos = len(t.src)
+	}
+
+	return Token{Type: EndTagToken, Data: name}
+}
+
+func (t *Tokenizer) startTag() Token {
+	t.pos++
+	token := Token{Type: StartTagToken, Data: t.name()}
+
+	for {
+		t.skipSpaces()
+		if t.pos >= len(t.src) {
+			break
+		}
+
+		c := t.src[t.pos]
+		if c == '>' {
+			t.pos++
+			break
+		}
+		if c == '/' {
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] == '>' {
+				t.pos++
+				token.Type = SelfClosingTagToken
+				break
+			}
+			continue
+		}
+
+		name, value := t.attr()
+		if len(name) == 0 {
+			t.pos++
+			continue
+		}
+		if token.Attrs == nil {
+			token.Attrs = make(map[string]string)
+		}
+		if _, ok := token.Attrs[name]; !ok {
+			token.Attrs[name] = value
+		}
+	}
+
+	if _, ok := rawTextElements[token.Data]; ok && token.Type == StartTagToken {
+		t.rawTag = token.Data
+	}
+
+	return token
+}
+
+func (t *Tokenizer) attr() (string, string) {
+	start := t.pos
+	for t.pos < len(t.src) && strings.IndexByte(" \t\n\r\f/>=", t.src[t.pos]) == -1 {
+		t.pos++
+	}
+	name := strings.ToLower(t.src[start:t.pos])
+
+	t.skipSpaces()
+	if t.pos >= len(t.src) || t.src[t.pos] != '=' {
+		return name, ""
+	}
+	t.pos++
+	t.skipSpaces()
+	if t.pos >= len(t.src) {
+		return name, ""
+	}
+
+	var value string
+	if quote := t.src[t.pos]; quote == '"' || quote == '\'' {
+		t.pos++
+		end := strings.IndexByte(t.src[t.pos:], quote)
+		if end == -1 {
+			end = len(t.src) - t.pos
+		}
+		value = t.src[t.pos : t.pos+end]
+		t.pos = min(t.pos+end+1, len(t.src))
+	} else {
+		start := t.pos
+		for t.pos < len(t.src) && strings.IndexByte(" \t\n\r\f>", t.src[t.pos]) == -1 {
+			t.pos++
+		}
+		value = t.src[start:t.pos]
+	}
+
+	return name, html.UnescapeString(value)
+}
+
+func (t *Tokenizer) name() string {
+	start := t.pos
+	for t.pos < len(t.src) && strings.IndexByte(" \t\n\r\f/>", t.src[t.pos]) == -1 {
+		t.pos++
+	}
+
+	return strings.ToLower(t.src[start:t.pos])
+}
+
+func (t *Tokenizer) skipSpaces() {
+	for t.pos < len(t.src) && strings.IndexByte(" \t\n\r\f", t.src[t.pos]) != -1 {
+		t.pos++
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func equalFoldASCII(s, lower string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -6,28 +6,65 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 	"yegorov-boris/affise-test-task/internal/models"
+	"yegorov-boris/affise-test-task/pkg/tracing"
 )
 
-type Client struct {
-	timeout time.Duration
-}
+type (
+	Client struct {
+		timeout    atomic.Int64
+		decorators []Decorator
+	}
+
+	// Decorator modifies outgoing requests, e.g. adds headers based on the request context.
+	Decorator = func(*http.Request)
+)
 
-func New(timeout time.Duration) *Client {
-	return &Client{
-		timeout: timeout,
+func New(timeout time.Duration, decorators ...Decorator) *Client {
+	c := &Client{
+		decorators: decorators,
 	}
+	c.SetTimeout(timeout)
+
+	return c
+}
+
+// SetTimeout changes the timeout of the requests sent after the call.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout.Store(int64(timeout))
 }
 
-func (c *Client) Get(ctx context.Context, link string) (models.Output, error) {
+// Get sends a GET request with the given header, which may be nil.
+// The latency of the output is the time of the request including reading the body.
+func (c *Client) Get(ctx context.Context, link string, header http.Header) (output models.Output, err error) {
+	ctx, span := tracing.Start(ctx, "GET", tracing.KindClient)
+	span.SetAttribute("url.full", link)
+	defer func() {
+		span.SetError(err)
+		if err == nil {
+			span.SetAttribute("http.response.status_code", output.StatusCode)
+		}
+		span.End()
+	}()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
 	if err != nil {
 		return models.Output{}, fmt.Errorf("failed to build http request: %w", err)
 	}
 
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	tracing.Inject(req)
+
+	for _, decorate := range c.decorators {
+		decorate(req)
+	}
+
 	client := &http.Client{
-		Timeout: c.timeout,
+		Timeout: time.Duration(c.timeout.Load()),
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
@@ -35,6 +72,7 @@ func (c *Client) Get(ctx context.Context, link string) (models.Output, error) {
 		},
 	}
 
+	start := time.Now()
 	res, err := client.Do(req)
 	if err != nil {
 		return models.Output{}, fmt.Errorf("failed to get response: %w", err)
@@ -51,5 +89,7 @@ func (c *Client) Get(ctx context.Context, link string) (models.Output, error) {
 		URL:        link,
 		StatusCode: res.StatusCode,
 		Body:       string(b),
+		Header:     res.Header,
+		Latency:    time.Since(start),
 	}, nil
 }
@@ -0,0 +1,44 @@
+package logctx
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// Handler adds the attributes stored in the context to every record logged with it,
+// e.g. logger.InfoContext(ctx, "message").
+type Handler struct {
+	slog.Handler
+}
+
+func NewHandler(inner slog.Handler) *Handler {
+	return &Handler{
+		Handler: inner,
+	}
+}
+
+// With returns a copy of the context carrying the attributes in addition to the inherited ones.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	inherited, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+
+	return context.WithValue(ctx, ctxKey{}, append(inherited[:len(inherited):len(inherited)], attrs...))
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewHandler(h.Handler.WithAttrs(attrs))
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return NewHandler(h.Handler.WithGroup(name))
+}
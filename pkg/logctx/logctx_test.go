@@ -0,0 +1,46 @@
+package logctx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&b, nil)))
+	parent := With(context.Background(), slog.String("request_id", "abc"))
+	child := With(parent, slog.String("job_id", "1"))
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "should log without context attributes",
+			ctx:  context.Background(),
+			want: "msg=test\n",
+		},
+		{
+			name: "should add context attributes",
+			ctx:  parent,
+			want: "msg=test request_id=abc\n",
+		},
+		{
+			name: "should add inherited context attributes",
+			ctx:  child,
+			want: "msg=test request_id=abc job_id=1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.Reset()
+			logger.InfoContext(tt.ctx, "test")
+			if got := b.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("Handle() wrote %q, want suffix %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package semaphore
+
+import "sync"
+
+// Semaphore is a non-blocking counting semaphore which can be resized at runtime.
+// When it shrinks, the slots in use are not revoked, new ones are not acquired until enough are released.
+type Semaphore struct {
+	m    sync.Mutex
+	size int
+	used int
+}
+
+func New(size int) *Semaphore {
+	return &Semaphore{
+		size: size,
+	}
+}
+
+func (s *Semaphore) TryAcquire() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.used >= s.size {
+		return false
+	}
+	s.used++
+
+	return true
+}
+
+func (s *Semaphore) Release() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.used--
+}
+
+func (s *Semaphore) Resize(size int) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.size = size
+}
+
+// Len returns the number of slots in use.
+func (s *Semaphore) Len() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.used
+}
+
+// Cap returns the number of slots.
+func (s *Semaphore) Cap() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.size
+}
@@ -0,0 +1,41 @@
+package semaphore
+
+import "testing"
+
+func TestSemaphore_Resize(t *testing.T) {
+	s := New(2)
+	for i := 0; i < 2; i++ {
+		if !s.TryAcquire() {
+			t.Errorf("TryAcquire() = false, want true for slot %d", i+1)
+		}
+	}
+
+	t.Run("should not acquire more slots than its size", func(t *testing.T) {
+		if s.TryAcquire() {
+			t.Error("TryAcquire() = true, want false")
+		}
+	})
+
+	t.Run("should acquire new slots when it grows", func(t *testing.T) {
+		s.Resize(3)
+		if !s.TryAcquire() {
+			t.Error("TryAcquire() = false, want true")
+		}
+	})
+
+	t.Run("should keep the slots in use when it shrinks", func(t *testing.T) {
+		s.Resize(1)
+		if s.Len() != 3 {
+			t.Errorf("Len() = %d, want 3", s.Len())
+		}
+		s.Release()
+		s.Release()
+		if s.TryAcquire() {
+			t.Error("TryAcquire() = true, want false while the slots in use exceed the size")
+		}
+		s.Release()
+		if !s.TryAcquire() {
+			t.Error("TryAcquire() = false, want true")
+		}
+	})
+}
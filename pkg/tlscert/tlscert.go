@@ -0,0 +1,98 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate loaded from files and reloads it when the files change,
+// so certificates can be rotated without restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	onReload func(error)
+	m        sync.Mutex
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	checked  time.Time
+}
+
+// New loads the certificate. The files are checked for changes at most once per interval,
+// onReload is called after every reload with its error, the previous certificate is kept when it fails.
+func New(certFile, keyFile string, interval time.Duration, onReload func(error)) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		onReload: onReload,
+	}
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate is meant to be used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if time.Since(r.checked) < r.interval {
+		return r.cert, nil
+	}
+	r.checked = time.Now()
+
+	certMod, keyMod, err := r.modTimes()
+	if err == nil && certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return r.cert, nil
+	}
+
+	if err == nil {
+		err = r.load(certMod, keyMod)
+	}
+	if r.onReload != nil {
+		r.onReload(err)
+	}
+
+	return r.cert, nil
+}
+
+func (r *Reloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.checked = time.Now()
+
+	return nil
+}
+
+func (r *Reloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat %q: %w", r.certFile, err)
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat %q: %w", r.keyFile, err)
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
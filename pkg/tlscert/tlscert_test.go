@@ -0,0 +1,115 @@
+package tlscert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReloader_GetCertificate(t *testing.T) {
+	certFile, keyFile := "./cert.pem", "./key.pem"
+	first, err := writeCert(certFile, keyFile, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Errorf("failed to write certificate: %s", err)
+		return
+	}
+
+	var reloadErr error
+	r, err := New(certFile, keyFile, 0, func(err error) {
+		reloadErr = err
+	})
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	t.Run("should serve the loaded certificate", func(t *testing.T) {
+		cert, err := r.GetCertificate(nil)
+		if err != nil || !bytes.Equal(cert.Certificate[0], first) {
+			t.Errorf("GetCertificate() = %v, want the loaded certificate", err)
+		}
+	})
+
+	t.Run("should reload the certificate when files change", func(t *testing.T) {
+		second, err := writeCert(certFile, keyFile, time.Now())
+		if err != nil {
+			t.Errorf("failed to write certificate: %s", err)
+			return
+		}
+		cert, err := r.GetCertificate(nil)
+		if err != nil || reloadErr != nil || !bytes.Equal(cert.Certificate[0], second) {
+			t.Errorf("GetCertificate() = %v, reload error = %v, want the new certificate", err, reloadErr)
+		}
+	})
+
+	t.Run("should keep the certificate when reload fails", func(t *testing.T) {
+		if err := os.WriteFile(certFile, []byte("invalid"), 0644); err != nil {
+			t.Errorf("failed to write %q: %s", certFile, err)
+			return
+		}
+		if err := os.Chtimes(certFile, time.Now(), time.Now().Add(time.Hour)); err != nil {
+			t.Errorf("failed to touch %q: %s", certFile, err)
+			return
+		}
+		cert, err := r.GetCertificate(nil)
+		if err != nil || cert == nil {
+			t.Errorf("GetCertificate() = %v, want the previous certificate", err)
+		}
+		if reloadErr == nil {
+			t.Error("reload error = nil, want an error")
+		}
+	})
+
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Remove(name); err != nil {
+			t.Errorf("rm %q failed: %s", name, err)
+		}
+	}
+}
+
+// writeCert writes a self-signed certificate with the given modification time and returns its DER bytes.
+func writeCert(certFile, keyFile string, modTime time.Time) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			return nil, err
+		}
+	}
+
+	return der, nil
+}
@@ -0,0 +1,33 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+// New generates a random 256-bit token and returns it along with its hash.
+// Only the hash is supposed to be stored.
+func New() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	t := hex.EncodeToString(b)
+
+	return t, Hash(t), nil
+}
+
+func Hash(t string) string {
+	sum := sha256.Sum256([]byte(t))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify compares the token with the stored hash in constant time.
+func Verify(t, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(t)), []byte(hash)) == 1
+}
@@ -0,0 +1,49 @@
+package token
+
+import "testing"
+
+func TestVerify(t *testing.T) {
+	valid, hash, err := New()
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+	other, _, err := New()
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{
+			name:  "should accept the token matching the hash",
+			token: valid,
+			want:  true,
+		},
+		{
+			name:  "should reject another token",
+			token: other,
+			want:  false,
+		},
+		{
+			name:  "should reject an empty token",
+			token: "",
+			want:  false,
+		},
+		{
+			name:  "should reject the hash itself",
+			token: hash,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.token, hash); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+
+	queueSize     = 2048
+	batchSize     = 512
+	flushInterval = time.Second
+)
+
+type (
+	SpanKind   int
+	StatusCode int
+
+	Exporter interface {
+		Export(serviceName string, spans []SpanData) error
+	}
+
+	// Tracer creates spans and exports the finished ones in batches in background.
+	Tracer struct {
+		serviceName string
+		exporter    Exporter
+		onError     func(error)
+		queue       chan SpanData
+		done        chan struct{}
+		shutdown    sync.Once
+		stopped     chan struct{}
+	}
+
+	// Span is safe to use when nil, so code can be traced regardless of the tracer configuration.
+	Span struct {
+		tracer *Tracer
+		m      sync.Mutex
+		data   SpanData
+		ended  bool
+	}
+
+	SpanData struct {
+		SpanContext
+		ParentSpanID SpanID
+		Name         string
+		Kind         SpanKind
+		Start        time.Time
+		End          time.Time
+		Attributes   map[string]any
+		Status       StatusCode
+		StatusMsg    string
+	}
+
+	spanKey struct{}
+)
+
+// New starts a tracer, errors of the exporter are passed to onError.
+func New(serviceName string, exporter Exporter, onError func(error)) *Tracer {
+	t := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		onError:     onError,
+		queue:       make(chan SpanData, queueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+// Start creates a span. Its parent is either the span from the context or the remote parent
+// extracted from an incoming request, otherwise a new trace is started.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Kind:       kind,
+			Start:      time.Now(),
+			Attributes: make(map[string]any),
+		},
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentSpanID = parent.data.SpanID
+		span.data.Sampled = parent.data.Sampled
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(SpanContext); ok {
+		span.data.TraceID = remote.TraceID
+		span.data.ParentSpanID = remote.SpanID
+		span.data.Sampled = remote.Sampled
+	} else {
+		_, _ = rand.Read(span.data.TraceID[:])
+		span.data.Sampled = true
+	}
+	_, _ = rand.Read(span.data.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Shutdown exports the queued spans and stops the tracer.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.shutdown.Do(func() {
+		close(t.done)
+	})
+
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := t.exporter.Export(t.serviceName, batch); err != nil && t.onError != nil {
+			t.onError(err)
+		}
+		batch = make([]SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.done:
+			for {
+				select {
+				case data := <-t.queue:
+					batch = append(batch, data)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case <-t.done:
+	case t.queue <- data:
+	default:
+		// the exporter does not keep up, the span is dropped
+	}
+}
+
+// Start creates a child of the span from the context, it does nothing when the context has no span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.Start(ctx, name, kind)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+
+	return span
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+// SetAttribute sets a string, bool, integer or float attribute.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.data.Attributes[key] = value
+}
+
+// SetError marks the span as failed, it does nothing when err is nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.SetStatus(StatusError, err.Error())
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.data.Status = code
+	s.data.StatusMsg = msg
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.m.Lock()
+	if s.ended {
+		s.m.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = make(map[string]any, len(s.data.Attributes))
+	for k, v := range s.data.Attributes {
+		data.Attributes[k] = v
+	}
+	s.m.Unlock()
+
+	if data.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantSampled bool
+		wantErr     bool
+	}{
+		{
+			name:        "should parse a sampled traceparent",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantSampled: true,
+			wantErr:     false,
+		},
+		{
+			name:        "should parse a not sampled traceparent",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			wantSampled: false,
+			wantErr:     false,
+		},
+		{
+			name:        "should fail for a zero trace ID",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+		{
+			name:        "should fail for a malformed value",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-01",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.traceparent)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTraceparent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if sc.Sampled != tt.wantSampled {
+				t.Errorf("ParseTraceparent() sampled = %v, want %v", sc.Sampled, tt.wantSampled)
+			}
+			if got := sc.Traceparent(); got != tt.traceparent {
+				t.Errorf("Traceparent() = %q, want %q", got, tt.traceparent)
+			}
+		})
+	}
+}
+
+func TestTracer(t *testing.T) {
+	name := "./spans.json"
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tracer := New("test", NewFileExporter(name), func(err error) {
+		t.Errorf("Export() error = %v", err)
+	})
+
+	h := http.Header{}
+	h.Set("traceparent", traceparent)
+	ctx, server := tracer.Start(Extract(context.Background(), h), "POST /links", KindServer)
+	ctx, client := Start(ctx, "GET", KindClient)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost", nil)
+	if err != nil {
+		t.Errorf("failed to build http request: %s", err)
+		return
+	}
+	Inject(req)
+	client.End()
+	server.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+		return
+	}
+
+	t.Run("should continue the incoming trace", func(t *testing.T) {
+		if got := server.SpanContext().TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("trace ID = %q, want the incoming one", got)
+		}
+		if got := client.SpanContext().TraceID; got != server.SpanContext().TraceID {
+			t.Errorf("child trace ID = %q, want %q", got, server.SpanContext().TraceID)
+		}
+	})
+
+	t.Run("should propagate the client span on outgoing requests", func(t *testing.T) {
+		if got, want := req.Header.Get("traceparent"), client.SpanContext().Traceparent(); got != want {
+			t.Errorf("traceparent = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should export finished spans", func(t *testing.T) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Errorf("failed to read %q: %s", name, err)
+			return
+		}
+		var exported otlpRequest
+		if err := json.Unmarshal(b, &exported); err != nil {
+			t.Errorf("failed to JSON decode %q: %s", name, err)
+			return
+		}
+		spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+		if len(spans) != 2 {
+			t.Errorf("exported %d spans, want 2", len(spans))
+			return
+		}
+		if spans[0].ParentSpanID != server.SpanContext().SpanID.String() {
+			t.Errorf("client span parent = %q, want %q", spans[0].ParentSpanID, server.SpanContext().SpanID)
+		}
+		if spans[1].ParentSpanID != "00f067aa0ba902b7" {
+			t.Errorf("server span parent = %q, want the remote one", spans[1].ParentSpanID)
+		}
+	})
+
+	t.Run("should not create spans without a parent", func(t *testing.T) {
+		if _, span := Start(context.Background(), "orphan", KindInternal); span != nil {
+			t.Error("Start() created a span for a context without a span")
+		}
+	})
+
+	if err := os.Remove(name); err != nil {
+		t.Errorf("rm %q failed: %s", name, err)
+	}
+}
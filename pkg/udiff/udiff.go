@@ -0,0 +1,236 @@
+// Package udiff makes unified diffs of texts line by line with the Myers algorithm.
+package udiff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ContextLines is the number of unchanged lines around the changes in hunks.
+const ContextLines = 3
+
+// ErrTooManyChanges is returned when the texts differ in more lines than allowed.
+var ErrTooManyChanges = errors.New("too many changed lines")
+
+// ErrTooManyLines is returned when the texts have more lines than allowed.
+var ErrTooManyLines = errors.New("too many lines")
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	// a and b are the line indexes in the old and the new texts
+	a, b int
+}
+
+// Unified returns the diff of the texts in the unified format, an empty string for equal texts.
+// maxChanges limits the number of deleted and inserted lines and maxLines the number of lines of both texts,
+// as the work grows with them.
+func Unified(oldName, newName, oldText, newText string, maxChanges, maxLines int) (string, error) {
+	if oldText == newText {
+		return "", nil
+	}
+
+	if strings.Count(oldText, "\n")+strings.Count(newText, "\n") > maxLines {
+		return "", ErrTooManyLines
+	}
+
+	a, b := splitLines(oldText), splitLines(newText)
+	ops, err := diff(a, b, maxChanges)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		writeHunk(&sb, ops[h[0]:h[1]], a, b)
+	}
+
+	return sb.String(), nil
+}
+
+func splitLines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diff finds the shortest edit script turning a into b.
+func diff(a, b []string, maxChanges int) ([]op, error) {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		if d > maxChanges {
+			return nil, ErrTooManyChanges
+		}
+
+		// only the diagonals -d..d are reachable with d changes, so only they are kept
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	// walk the trace back from the end collecting the operations in reverse,
+	// trace[d] keeps the furthest points reached with d-1 changes on the diagonals -d..d
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int {
+			// nothing is reached outside of the diagonals before the first step
+			if k < -d || k > d {
+				return 0
+			}
+
+			return v[d+k]
+		}
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, a: x, b: y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, op{kind: opInsert, a: x, b: y})
+			} else {
+				x--
+				ops = append(ops, op{kind: opDelete, a: x, b: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops, nil
+}
+
+// hunks groups the changes with their context into ranges of ops.
+func hunks(ops []op) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+
+		start := max(i-ContextLines, 0)
+		end := i
+		// extend the hunk while the next change is close enough to share the context
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != opEqual {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*ContextLines {
+				break
+			}
+		}
+		end = min(end+ContextLines, len(ops))
+
+		if len(result) != 0 && result[len(result)-1][1] >= start {
+			result[len(result)-1][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+		i = end - 1
+	}
+
+	return result
+}
+
+func writeHunk(sb *strings.Builder, ops []op, a, b []string) {
+	oldStart, newStart := ops[0].a, ops[0].b
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			oldCount++
+			newCount++
+		case opDelete:
+			oldCount++
+		case opInsert:
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			writeLine(sb, ' ', a[o.a])
+		case opDelete:
+			writeLine(sb, '-', a[o.a])
+		case opInsert:
+			writeLine(sb, '+', b[o.b])
+		}
+	}
+}
+
+// hunkRange formats the 1-based start and the count, an empty range starts at the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
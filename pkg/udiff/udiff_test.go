@@ -0,0 +1,113 @@
+package udiff
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldText    string
+		newText    string
+		maxChanges int
+		maxLines   int
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "should return nothing for equal texts",
+			oldText:    "a\nb\n",
+			newText:    "a\nb\n",
+			maxChanges: 10,
+			maxLines:   100,
+			want:       "",
+		},
+		{
+			name:       "should show a changed line with context",
+			oldText:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			newText:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			maxChanges: 10,
+			maxLines:   100,
+			want:       "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:       "should split distant changes into hunks",
+			oldText:    "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			newText:    "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			maxChanges: 10,
+			maxLines:   100,
+			want:       "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name:       "should show insertions into an empty text",
+			oldText:    "",
+			newText:    "a\n",
+			maxChanges: 10,
+			maxLines:   100,
+			want:       "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:       "should mark a missing newline at the end",
+			oldText:    "a\n",
+			newText:    "a",
+			maxChanges: 10,
+			maxLines:   100,
+			want:       "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			name:       "should fail for too many changes",
+			oldText:    "a\nb\nc\n",
+			newText:    "d\ne\nf\n",
+			maxChanges: 2,
+			maxLines:   100,
+			wantErr:    true,
+		},
+		{
+			name:       "should fail for too many lines",
+			oldText:    "a\nb\nc\n",
+			newText:    "a\nb\nd\n",
+			maxChanges: 10,
+			maxLines:   5,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unified("old", "new", tt.oldText, tt.newText, tt.maxChanges, tt.maxLines)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unified() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Unified() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified_large(t *testing.T) {
+	lines := 20000
+	maxChanges := 1000
+	var oldText, newText strings.Builder
+	for i := 0; i < lines; i++ {
+		oldText.WriteString("old " + strconv.Itoa(i) + "\n")
+		newText.WriteString("new " + strconv.Itoa(i) + "\n")
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := Unified("old", "new", oldText.String(), newText.String(), maxChanges, 2*lines)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, ErrTooManyChanges) {
+		t.Errorf("Unified() error = %v, want %v", err, ErrTooManyChanges)
+	}
+	// the trace of the changes grows with their square, not with the lines
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("Unified() allocated %d bytes, want at most %d", allocated, 64<<20)
+	}
+}
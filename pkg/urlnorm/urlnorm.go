@@ -0,0 +1,97 @@
+// Package urlnorm normalizes URLs, so equivalent ones compare equal (RFC 3986, section 6.2.2).
+package urlnorm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize lower-cases the scheme and the host, removes the default port and dot segments of the path.
+// Query parameters are sorted by name when sortQuery is set, the order of the values of a parameter is kept.
+func Normalize(rawURL string, sortQuery bool) (string, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if len(u.Host) != 0 {
+		host, port := strings.ToLower(u.Hostname()), u.Port()
+		if port == defaultPorts[u.Scheme] {
+			port = ""
+		}
+
+		switch {
+		case len(port) != 0:
+			u.Host = net.JoinHostPort(host, port)
+		case strings.Contains(host, ":"):
+			u.Host = "[" + host + "]"
+		default:
+			u.Host = host
+		}
+	}
+
+	if len(u.Opaque) == 0 {
+		path := removeDotSegments(u.EscapedPath())
+		if len(path) == 0 && len(u.Host) != 0 {
+			path = "/"
+		}
+
+		if u.Path, err = url.PathUnescape(path); err != nil {
+			return "", fmt.Errorf("failed to unescape path: %w", err)
+		}
+		u.RawPath = path
+	}
+
+	if sortQuery && len(u.RawQuery) != 0 {
+		params := strings.Split(u.RawQuery, "&")
+		sort.SliceStable(params, func(i, j int) bool {
+			nameI, _, _ := strings.Cut(params[i], "=")
+			nameJ, _, _ := strings.Cut(params[j], "=")
+
+			return nameI < nameJ
+		})
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String(), nil
+}
+
+// removeDotSegments removes "." and ".." segments of the path (RFC 3986, section 5.2.4).
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	var out []string
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			// the leading empty segment of an absolute path is never removed
+			if len(out) > 1 || (len(out) == 1 && len(out[0]) != 0) {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, s)
+		}
+	}
+
+	return strings.Join(out, "/")
+}
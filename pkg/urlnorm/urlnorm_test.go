@@ -0,0 +1,84 @@
+package urlnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	type args struct {
+		rawURL    string
+		sortQuery bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "should lower-case the scheme and the host",
+			args: args{rawURL: "HTTPS://Example.COM/Path"},
+			want: "https://example.com/Path",
+		},
+		{
+			name: "should remove the default port",
+			args: args{rawURL: "http://example.com:80/a"},
+			want: "http://example.com/a",
+		},
+		{
+			name: "should keep a non-default port",
+			args: args{rawURL: "https://example.com:8443/a"},
+			want: "https://example.com:8443/a",
+		},
+		{
+			name: "should keep brackets of an IPv6 host",
+			args: args{rawURL: "http://[::1]:80/a"},
+			want: "http://[::1]/a",
+		},
+		{
+			name: "should remove dot segments",
+			args: args{rawURL: "http://example.com/a/./b/../c/"},
+			want: "http://example.com/a/c/",
+		},
+		{
+			name: "should not go above the root",
+			args: args{rawURL: "http://example.com/../a/.."},
+			want: "http://example.com/",
+		},
+		{
+			name: "should add the root path",
+			args: args{rawURL: "http://example.com"},
+			want: "http://example.com/",
+		},
+		{
+			name: "should keep escaped characters of the path",
+			args: args{rawURL: "http://example.com/a%2Fb/./c"},
+			want: "http://example.com/a%2Fb/c",
+		},
+		{
+			name: "should keep the query order by default",
+			args: args{rawURL: "http://example.com/?b=1&a=2"},
+			want: "http://example.com/?b=1&a=2",
+		},
+		{
+			name: "should sort query parameters by name keeping the order of values",
+			args: args{rawURL: "http://example.com/?b=1&a=2&b=0", sortQuery: true},
+			want: "http://example.com/?a=2&b=1&b=0",
+		},
+		{
+			name:    "should fail for an invalid URL",
+			args:    args{rawURL: "foo"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.args.rawURL, tt.args.sortQuery)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}